@@ -41,6 +41,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"github.com/jwells131313/goethe"
 )
@@ -131,6 +132,24 @@ func (cache *cacheData) Compute(key interface{}) (interface{}, error) {
 	return cache.internalCompute(key)
 }
 
+func (cache *cacheData) ComputeContext(ctx context.Context, key interface{}) (interface{}, error) {
+	tid := gd.GetThreadID()
+	if tid < 0 {
+		replyChan := make(chan (*onGoetheReply), 1)
+
+		gd.Go(cache.channelInternalComputeContext, ctx, key, replyChan)
+
+		select {
+		case reply := <-replyChan:
+			return reply.value, reply.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return cache.internalComputeContext(ctx, key)
+}
+
 func (cache *cacheData) GetCalculator() Computable {
 	return cache.calculater
 }
@@ -239,9 +258,30 @@ func (cache *cacheData) channelInternalCompute(key interface{}, reply chan (*onG
 	}
 }
 
+func (cache *cacheData) channelInternalComputeContext(ctx context.Context, key interface{}, reply chan (*onGoetheReply)) {
+	value, err := cache.internalComputeContext(ctx, key)
+
+	reply <- &onGoetheReply{
+		value: value,
+		err:   err,
+	}
+}
+
 func (cache *cacheData) internalCompute(key interface{}) (interface{}, error) {
-	cache.lock.Lock()
-	defer cache.lock.Unlock()
+	return cache.internalComputeContext(context.Background(), key)
+}
+
+func (cache *cacheData) internalComputeContext(ctx context.Context, key interface{}) (interface{}, error) {
+	err := cache.lock.WriteLockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cache.lock.WriteUnlock()
+
+	if err = ctx.Err(); err != nil {
+		// The lock became free at the same time the context was done
+		return nil, err
+	}
 
 	value, found := cache.cache[key]
 	if found {
@@ -41,9 +41,11 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 const (
@@ -254,3 +256,30 @@ func itoi(in interface{}) (int, error) {
 
 	return retVal, nil
 }
+
+func TestComputeContextCancelled(t *testing.T) {
+	release := make(chan bool)
+	started := make(chan bool, 2)
+
+	c, err := NewComputeFunctionCache(func(key interface{}) (interface{}, error) {
+		started <- true
+		<-release
+		return key, nil
+	})
+	assert.Nil(t, err, "no new cache")
+
+	go c.Compute("slow")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = c.ComputeContext(ctx, "other")
+	assert.Equal(t, context.DeadlineExceeded, err, "expected deadline while cache was busy")
+
+	close(release)
+
+	reply, err := c.ComputeContext(context.Background(), "slow")
+	assert.Nil(t, err, "getting slow value")
+	assert.Equal(t, "slow", reply, "cached value not returned")
+}
@@ -40,7 +40,10 @@
 
 package cache
 
-import "github.com/jwells131313/goethe"
+import (
+	"context"
+	"github.com/jwells131313/goethe"
+)
 
 type carCache struct {
 	lock         goethe.Lock
@@ -104,6 +107,24 @@ func (cc *carCache) Compute(key interface{}) (interface{}, error) {
 	return cc.internalCompute(key)
 }
 
+func (cc *carCache) ComputeContext(ctx context.Context, key interface{}) (interface{}, error) {
+	tid := gd.GetThreadID()
+	if tid < 0 {
+		replyChan := make(chan (*onGoetheReply), 1)
+
+		gd.Go(cc.channelInternalComputeContext, ctx, key, replyChan)
+
+		select {
+		case reply := <-replyChan:
+			return reply.value, reply.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return cc.internalComputeContext(ctx, key)
+}
+
 func (cc *carCache) GetCalculator() Computable {
 	return cc.calculator
 }
@@ -181,10 +202,31 @@ func (cc *carCache) channelInternalCompute(key interface{}, reply chan (*onGoeth
 	}
 }
 
+func (cc *carCache) channelInternalComputeContext(ctx context.Context, key interface{}, reply chan (*onGoetheReply)) {
+	value, err := cc.internalComputeContext(ctx, key)
+
+	reply <- &onGoetheReply{
+		value: value,
+		err:   err,
+	}
+}
+
 func (cc *carCache) internalCompute(key interface{}) (interface{}, error) {
-	cc.lock.WriteLock()
+	return cc.internalComputeContext(context.Background(), key)
+}
+
+func (cc *carCache) internalComputeContext(ctx context.Context, key interface{}) (interface{}, error) {
+	err := cc.lock.WriteLockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer cc.lock.WriteUnlock()
 
+	if err = ctx.Err(); err != nil {
+		// The lock became free at the same time the context was done
+		return nil, err
+	}
+
 	value, found := cc.T1.Get(key)
 	if found {
 		// Cache hit
@@ -40,6 +40,8 @@
 
 package cache
 
+import "context"
+
 // Computable should be implemented in order to build the values for the cache based
 // on the incoming keys.  The method will only be called if the cache does not already
 // have a value for the key (unless the key/value pair has been removed due to age or
@@ -61,6 +63,10 @@ type CycleHandler func(interface{}) error
 type Cache interface {
 	// Computable The methods to call to get the value given a key
 	Computable
+	// ComputeContext is the same as Compute except that it will stop waiting for
+	// the cache if the context is done, in which case the error of the context
+	// is returned
+	ComputeContext(ctx context.Context, key interface{}) (interface{}, error)
 	// GetCalculator The calculator associated with this cache
 	GetCalculator() Computable
 	// GetCycleHandler The cycle handler associated with this cache (may be nil)
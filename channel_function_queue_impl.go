@@ -0,0 +1,284 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// channelPollDuration is how long the channel of a FunctionQueue waits
+	// on a queue that does not support DequeueContext before checking if it
+	// has been closed
+	channelPollDuration = 100 * time.Millisecond
+
+	// channelRequeueDuration is how long a function that was dequeued but
+	// not received when the channel is closed waits for room on the queue
+	channelRequeueDuration = 1 * time.Second
+)
+
+type channelFunctionQueue struct {
+	source <-chan func()
+	buffer *FunctionQueueImpl
+
+	// forwarding is one while forward holds a function from the
+	// channel that is waiting for room on the buffer.  Guarded by
+	// the mutex of the buffer
+	forwarding int
+}
+
+// NewChannelFunctionQueue creates a function queue fed by the given channel,
+// which lets existing work channels be used with a Pool.  Functions are moved
+// from the channel onto the queue as there is room, so that the size of the
+// queue and the state change callback include the functions sent on the
+// channel.  Functions given to Enqueue go directly on the queue.  The size
+// and capacity of the queue include the size and capacity of the channel,
+// and the size includes a function taken from the channel that is waiting
+// for room on the queue.  The goroutine moving the functions runs for as
+// long as the channel is open, there is no other way to stop it.  Once the
+// channel is closed it exits after the function it holds, if any, has been
+// put on the queue
+func NewChannelFunctionQueue(source <-chan func()) ContextFunctionQueue {
+	capacity := cap(source)
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	retVal := &channelFunctionQueue{
+		source: source,
+		buffer: NewBoundedFunctionQueue(uint32(capacity)).(*FunctionQueueImpl),
+	}
+
+	go retVal.forward()
+
+	return retVal
+}
+
+// forward moves functions from the channel onto the queue until the channel is closed
+func (cq *channelFunctionQueue) forward() {
+	buffer := cq.buffer
+
+	for userCall := range cq.source {
+		if userCall == nil {
+			continue
+		}
+
+		buffer.mux.Lock()
+
+		cq.forwarding = 1
+		waitFor(buffer.cond, -1, func() bool {
+			return uint32(len(buffer.queue)) < buffer.capacity
+		})
+
+		buffer.enqueueLocked(userCall, nil)
+		cq.forwarding = 0
+
+		buffer.mux.Unlock()
+	}
+}
+
+// Enqueue queues a function to be run in the pool.  Returns
+// ErrAtCapacity if the queue is currently at capacity
+func (cq *channelFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	return cq.buffer.Enqueue(userCall, args...)
+}
+
+// Dequeue returns a function to be run, waiting the given
+// duration.  If there is no message within the given
+// duration return the error returned will be ErrEmptyQueue
+func (cq *channelFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	return cq.buffer.Dequeue(duration)
+}
+
+// DequeueContext returns a function to be run, waiting until one
+// is available or the context is done.  If the context is done before
+// a function is available the error of the context is returned
+func (cq *channelFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	return cq.buffer.DequeueContext(ctx)
+}
+
+// GetCapacity gets the capacity of this queue and its channel
+func (cq *channelFunctionQueue) GetCapacity() uint32 {
+	return cq.buffer.GetCapacity() + uint32(cap(cq.source))
+}
+
+// GetSize returns the number of items currently in the queue and its channel
+func (cq *channelFunctionQueue) GetSize() int {
+	cq.buffer.mux.Lock()
+	defer cq.buffer.mux.Unlock()
+
+	return len(cq.buffer.queue) + cq.forwarding + len(cq.source)
+}
+
+// IsEmpty Returns true if this queue and its channel are currently empty
+func (cq *channelFunctionQueue) IsEmpty() bool {
+	return cq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be called whenever an enqueue,
+// including a function moved from the channel, or a dequeue changes the size
+// of queue
+func (cq *channelFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	if ch == nil {
+		cq.buffer.SetStateChangeCallback(nil)
+		return
+	}
+
+	cq.buffer.SetStateChangeCallback(func(FunctionQueue) {
+		ch(cq)
+	})
+}
+
+type functionQueueChannel struct {
+	queue   FunctionQueue
+	channel chan *FunctionDescriptor
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// requeueErr is set before done is closed
+	requeueErr error
+}
+
+// NewFunctionQueueChannel returns a channel that receives the functions
+// dequeued from the given queue, along with a Closer that stops functions
+// being dequeued and closes the channel.  A function that has been dequeued
+// but not received when the Closer is called is enqueued again with Enqueue,
+// or EnqueueWait if the queue is a BatchFunctionQueue, so it gets a new
+// EnqueuedTime and loses any tenant or priority it was given.  If it cannot
+// be enqueued again the Closer returns a RequeueError holding the function
+func NewFunctionQueueChannel(queue FunctionQueue) (<-chan *FunctionDescriptor, io.Closer) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	retVal := &functionQueueChannel{
+		queue:   queue,
+		channel: make(chan *FunctionDescriptor),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go retVal.run()
+
+	return retVal.channel, retVal
+}
+
+func (qc *functionQueueChannel) run() {
+	defer close(qc.done)
+	defer close(qc.channel)
+
+	for {
+		descriptor, err := qc.dequeue()
+		if qc.ctx.Err() != nil {
+			if err == nil {
+				qc.requeue(descriptor)
+			}
+
+			return
+		}
+		if err != nil {
+			if err != ErrEmptyQueue {
+				// The queue did not wait, so wait here rather than spin
+				select {
+				case <-time.After(channelPollDuration):
+				case <-qc.ctx.Done():
+				}
+			}
+
+			continue
+		}
+
+		select {
+		case qc.channel <- descriptor:
+		case <-qc.ctx.Done():
+			qc.requeue(descriptor)
+			return
+		}
+	}
+}
+
+// dequeue waits for the next function, using the context if the queue supports it
+func (qc *functionQueueChannel) dequeue() (*FunctionDescriptor, error) {
+	contextQueue, ok := qc.queue.(ContextFunctionQueue)
+	if !ok {
+		return qc.queue.Dequeue(channelPollDuration)
+	}
+
+	return contextQueue.DequeueContext(qc.ctx)
+}
+
+// requeue puts back a function that was dequeued but never received
+func (qc *functionQueueChannel) requeue(descriptor *FunctionDescriptor) {
+	var err error
+	if batchQueue, ok := qc.queue.(BatchFunctionQueue); ok {
+		err = batchQueue.EnqueueWait(channelRequeueDuration, descriptor.UserCall, descriptor.Args...)
+	} else {
+		err = qc.queue.Enqueue(descriptor.UserCall, descriptor.Args...)
+	}
+
+	if err != nil {
+		qc.requeueErr = &RequeueError{
+			Descriptor: descriptor,
+			Err:        err,
+		}
+	}
+}
+
+// Close stops functions being dequeued and waits for the channel to be closed.
+// Returns a RequeueError if a function that was dequeued could not be enqueued again
+func (qc *functionQueueChannel) Close() error {
+	qc.cancel()
+	<-qc.done
+
+	return qc.requeueErr
+}
+
+func (re *RequeueError) Error() string {
+	return fmt.Sprintf("function dequeued for a channel could not be enqueued again: %v", re.Err)
+}
+
+// Unwrap returns the error from enqueueing the function again
+func (re *RequeueError) Unwrap() error {
+	return re.Err
+}
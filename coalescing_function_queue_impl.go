@@ -0,0 +1,249 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type coalescingFunctionQueue struct {
+	mux     sync.Mutex
+	cond    *sync.Cond
+	changer func(queue FunctionQueue)
+
+	capacity  uint32
+	policy    CoalescePolicy
+	coalesced uint64
+
+	queue []*coalescingEntry
+	keyed map[string]*coalescingEntry
+}
+
+type coalescingEntry struct {
+	key        string
+	hasKey     bool
+	descriptor *FunctionDescriptor
+}
+
+// NewCoalescingFunctionQueue creates a new function queue with the given capacity
+// that coalesces functions with the same key according to the given policy
+func NewCoalescingFunctionQueue(userCapacity uint32, policy CoalescePolicy) CoalescingFunctionQueue {
+	retVal := &coalescingFunctionQueue{
+		capacity: userCapacity,
+		policy:   policy,
+		queue:    make([]*coalescingEntry, 0),
+		keyed:    make(map[string]*coalescingEntry),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal
+}
+
+// Enqueue queues a function without a key to be run in the pool.
+// Returns ErrAtCapacity if the queue is currently at capacity
+func (cq *coalescingFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	return cq.enqueue(&coalescingEntry{}, userCall, args)
+}
+
+// EnqueueKeyed queues a function with the given key to be run in the pool,
+// coalescing it with a queued function that has the same key
+func (cq *coalescingFunctionQueue) EnqueueKeyed(key string, userCall interface{}, args ...interface{}) error {
+	return cq.enqueue(&coalescingEntry{
+		key:    key,
+		hasKey: true,
+	}, userCall, args)
+}
+
+func (cq *coalescingFunctionQueue) enqueue(entry *coalescingEntry, userCall interface{}, args []interface{}) error {
+	if userCall == nil {
+		return nil
+	}
+
+	descriptor := &FunctionDescriptor{
+		UserCall:     userCall,
+		Args:         make([]interface{}, len(args)),
+		EnqueuedTime: time.Now(),
+	}
+
+	for index, arg := range args {
+		descriptor.Args[index] = arg
+	}
+
+	cq.mux.Lock()
+	defer cq.mux.Unlock()
+
+	if entry.hasKey {
+		if queued, found := cq.keyed[entry.key]; found {
+			cq.coalesced++
+
+			if cq.policy == CoalesceKeepLast {
+				queued.descriptor.UserCall = descriptor.UserCall
+				queued.descriptor.Args = descriptor.Args
+			}
+
+			return nil
+		}
+	}
+
+	if uint32(len(cq.queue)) >= cq.capacity {
+		return ErrAtCapacity
+	}
+
+	entry.descriptor = descriptor
+
+	cq.queue = append(cq.queue, entry)
+	if entry.hasKey {
+		cq.keyed[entry.key] = entry
+	}
+
+	cq.cond.Broadcast()
+	if cq.changer != nil {
+		go cq.changer(cq)
+	}
+
+	return nil
+}
+
+// Dequeue returns a function to be run, waiting the given
+// duration.  If there is no message within the given
+// duration return the error returned will be ErrEmptyQueue
+func (cq *coalescingFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	if duration < 0 {
+		duration = 0
+	}
+
+	cq.mux.Lock()
+	defer cq.mux.Unlock()
+
+	found := waitFor(cq.cond, duration, func() bool {
+		return len(cq.queue) > 0
+	})
+	if !found {
+		return nil, ErrEmptyQueue
+	}
+
+	return cq.dequeueLocked(), nil
+}
+
+// DequeueContext returns a function to be run, waiting until one
+// is available or the context is done.  If the context is done before
+// a function is available the error of the context is returned
+func (cq *coalescingFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	cq.mux.Lock()
+	defer cq.mux.Unlock()
+
+	if len(cq.queue) <= 0 {
+		closer := broadcastOnDone(ctx, cq.cond)
+		defer closer.Close()
+
+		for len(cq.queue) <= 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			cq.cond.Wait()
+		}
+	}
+
+	return cq.dequeueLocked(), nil
+}
+
+// dequeueLocked must have mutex held and the queue must not be empty
+func (cq *coalescingFunctionQueue) dequeueLocked() *FunctionDescriptor {
+	entry := cq.queue[0]
+	cq.queue[0] = nil
+	cq.queue = cq.queue[1:]
+
+	if entry.hasKey {
+		// Functions with this key enqueued from now on are queued again
+		delete(cq.keyed, entry.key)
+	}
+
+	if cq.changer != nil {
+		go cq.changer(cq)
+	}
+
+	return entry.descriptor
+}
+
+// GetCoalescePolicy returns the policy used when two functions have the same key
+func (cq *coalescingFunctionQueue) GetCoalescePolicy() CoalescePolicy {
+	return cq.policy
+}
+
+// GetCoalescedCount returns the number of functions that were coalesced
+func (cq *coalescingFunctionQueue) GetCoalescedCount() uint64 {
+	cq.mux.Lock()
+	defer cq.mux.Unlock()
+
+	return cq.coalesced
+}
+
+// GetCapacity gets the capacity of this queue
+func (cq *coalescingFunctionQueue) GetCapacity() uint32 {
+	return cq.capacity
+}
+
+// GetSize returns the number of items currently in the queue
+func (cq *coalescingFunctionQueue) GetSize() int {
+	cq.mux.Lock()
+	defer cq.mux.Unlock()
+
+	return len(cq.queue)
+}
+
+// IsEmpty Returns true if this queue is currently empty
+func (cq *coalescingFunctionQueue) IsEmpty() bool {
+	return cq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be
+// called whenever an enqueue or dequeue changes
+// the size of queue
+func (cq *coalescingFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	cq.mux.Lock()
+	defer cq.mux.Unlock()
+
+	cq.changer = ch
+}
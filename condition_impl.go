@@ -0,0 +1,174 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"time"
+)
+
+type goetheCondition struct {
+	lock *goetheLock
+
+	// waiters is guarded by the mutex of the lock and is in arrival order
+	waiters []*conditionWaiter
+}
+
+type conditionWaiter struct {
+	signalled bool
+}
+
+func (lock *goetheLock) NewCondition() Condition {
+	return &goetheCondition{
+		lock:    lock,
+		waiters: make([]*conditionWaiter, 0),
+	}
+}
+
+func (condition *goetheCondition) Await(d time.Duration) (bool, error) {
+	if d < -1 {
+		return false, ErrIllegalDuration
+	}
+
+	lock := condition.lock
+
+	tid := lock.parent.GetThreadID()
+	if tid < 0 {
+		return false, ErrNotGoetheThread
+	}
+
+	lock.goMux.Lock()
+
+	if tid != lock.holdingWriter {
+		lock.goMux.Unlock()
+		return false, ErrWriteLockNotHeld
+	}
+
+	if lock.getMyReadCount(tid) != 0 {
+		// Nobody could get the write lock to signal this thread
+		lock.goMux.Unlock()
+		return false, ErrReadLockHeld
+	}
+
+	savedCount := lock.writerCount
+
+	lock.writerCount = 0
+	lock.holdingWriter = -2
+	lock.parent.deadlocks.released(lock, tid, true)
+
+	waiter := &conditionWaiter{}
+	condition.waiters = append(condition.waiters, waiter)
+
+	lock.cond.Broadcast()
+
+	signalled := waitFor(lock.cond, d, func() bool {
+		return waiter.signalled
+	})
+	if !signalled {
+		condition.removeWaiter(waiter)
+	}
+
+	lock.goMux.Unlock()
+
+	_, err := lock.tryWriteLock(context.Background(), -1)
+	if err != nil {
+		// Only a DeadlockError, the lock is not held
+		return signalled, err
+	}
+
+	lock.goMux.Lock()
+	lock.writerCount = savedCount
+	lock.goMux.Unlock()
+
+	return signalled, nil
+}
+
+func (condition *goetheCondition) Signal() error {
+	return condition.signal(false)
+}
+
+func (condition *goetheCondition) SignalAll() error {
+	return condition.signal(true)
+}
+
+func (condition *goetheCondition) signal(all bool) error {
+	lock := condition.lock
+
+	tid := lock.parent.GetThreadID()
+	if tid < 0 {
+		return ErrNotGoetheThread
+	}
+
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	if tid != lock.holdingWriter {
+		return ErrWriteLockNotHeld
+	}
+
+	if len(condition.waiters) == 0 {
+		return nil
+	}
+
+	numberToSignal := 1
+	if all {
+		numberToSignal = len(condition.waiters)
+	}
+
+	for _, waiter := range condition.waiters[:numberToSignal] {
+		waiter.signalled = true
+	}
+	condition.waiters = condition.waiters[numberToSignal:]
+
+	lock.cond.Broadcast()
+
+	return nil
+}
+
+// removeWaiter must have the mutex of the lock held
+func (condition *goetheCondition) removeWaiter(waiter *conditionWaiter) {
+	for index, current := range condition.waiters {
+		if current == waiter {
+			condition.waiters = append(condition.waiters[:index], condition.waiters[index+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"sync"
+	"time"
+)
+
+type countDownLatch struct {
+	parent *StandardThreadUtilities
+
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	count    int32
+	arrivals []int64
+}
+
+func newCountDownLatch(par *StandardThreadUtilities, count int32) (CountDownLatch, error) {
+	if count < 0 {
+		return nil, ErrIllegalCount
+	}
+
+	retVal := &countDownLatch{
+		parent:   par,
+		count:    count,
+		arrivals: make([]int64, 0),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal, nil
+}
+
+func (latch *countDownLatch) CountDown() {
+	tid := latch.parent.GetThreadID()
+
+	latch.mux.Lock()
+	defer latch.mux.Unlock()
+
+	if latch.count <= 0 {
+		return
+	}
+
+	latch.count--
+	latch.arrivals = append(latch.arrivals, tid)
+
+	if latch.count <= 0 {
+		latch.cond.Broadcast()
+	}
+}
+
+func (latch *countDownLatch) Await(d time.Duration) (bool, error) {
+	if d < -1 {
+		return false, ErrIllegalDuration
+	}
+
+	latch.mux.Lock()
+	defer latch.mux.Unlock()
+
+	return waitFor(latch.cond, d, func() bool {
+		return latch.count <= 0
+	}), nil
+}
+
+func (latch *countDownLatch) GetCount() int32 {
+	latch.mux.Lock()
+	defer latch.mux.Unlock()
+
+	return latch.count
+}
+
+func (latch *countDownLatch) GetArrivals() []int64 {
+	latch.mux.Lock()
+	defer latch.mux.Unlock()
+
+	return append([]int64{}, latch.arrivals...)
+}
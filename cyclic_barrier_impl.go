@@ -0,0 +1,181 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"sync"
+	"time"
+)
+
+type cyclicBarrier struct {
+	parent *StandardThreadUtilities
+
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	parties    int32
+	action     func([]int64)
+	generation *barrierGeneration
+}
+
+// barrierGeneration is one use of the barrier.  Waiting threads hold on to
+// the generation they arrived in so they are not confused by later uses
+type barrierGeneration struct {
+	arrivals []int64
+	tripped  bool
+	broken   bool
+}
+
+func newCyclicBarrier(par *StandardThreadUtilities, parties int32, action func([]int64)) (CyclicBarrier, error) {
+	if parties < 1 {
+		return nil, ErrIllegalCount
+	}
+
+	retVal := &cyclicBarrier{
+		parent:     par,
+		parties:    parties,
+		action:     action,
+		generation: &barrierGeneration{},
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal, nil
+}
+
+func (barrier *cyclicBarrier) Await(d time.Duration) ([]int64, error) {
+	if d < -1 {
+		return nil, ErrIllegalDuration
+	}
+
+	tid := barrier.parent.GetThreadID()
+
+	barrier.mux.Lock()
+	defer barrier.mux.Unlock()
+
+	generation := barrier.generation
+	if generation.broken {
+		return nil, ErrBarrierBroken
+	}
+
+	generation.arrivals = append(generation.arrivals, tid)
+
+	if int32(len(generation.arrivals)) >= barrier.parties {
+		if barrier.action != nil {
+			err := barrier.runAction(generation)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		generation.tripped = true
+		barrier.generation = &barrierGeneration{}
+
+		barrier.cond.Broadcast()
+
+		return append([]int64{}, generation.arrivals...), nil
+	}
+
+	done := waitFor(barrier.cond, d, func() bool {
+		return generation.tripped || generation.broken
+	})
+	if !done {
+		generation.broken = true
+		barrier.cond.Broadcast()
+
+		return nil, ErrTimeout
+	}
+
+	if generation.broken {
+		return nil, ErrBarrierBroken
+	}
+
+	return append([]int64{}, generation.arrivals...), nil
+}
+
+// runAction calls the action with the arrivals of the generation.  If the
+// action panics the generation is broken, releasing the waiting parties, and
+// the panic is handled with the panic policy of the parent.  Must have mutex held
+func (barrier *cyclicBarrier) runAction(generation *barrierGeneration) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		generation.broken = true
+		barrier.cond.Broadcast()
+
+		err = barrier.parent.handlePanic(recovered, nil, barrier.parent.GetPanicPolicy())
+	}()
+
+	barrier.action(append([]int64{}, generation.arrivals...))
+
+	return nil
+}
+
+func (barrier *cyclicBarrier) Reset() {
+	barrier.mux.Lock()
+	defer barrier.mux.Unlock()
+
+	barrier.generation.broken = true
+	barrier.generation = &barrierGeneration{}
+
+	barrier.cond.Broadcast()
+}
+
+func (barrier *cyclicBarrier) IsBroken() bool {
+	barrier.mux.Lock()
+	defer barrier.mux.Unlock()
+
+	return barrier.generation.broken
+}
+
+func (barrier *cyclicBarrier) GetParties() int32 {
+	return barrier.parties
+}
+
+func (barrier *cyclicBarrier) GetNumberWaiting() int32 {
+	barrier.mux.Lock()
+	defer barrier.mux.Unlock()
+
+	return int32(len(barrier.generation.arrivals))
+}
@@ -0,0 +1,351 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// deadlockDetector keeps its own copy of which threads hold and are waiting
+// for which locks so that it never needs the mutex of any lock other than
+// the one it was called from.  The mutex of the detector is always the last
+// one taken
+type deadlockDetector struct {
+	enabled int32
+
+	mux        sync.Mutex
+	failWaiter bool
+	handler    func(*DeadlockError)
+
+	holders map[*goetheLock]*lockHolders
+	waiting map[int64]*waitingFor
+}
+
+type lockHolders struct {
+	writer         int64
+	readers        map[int64]bool
+	waitingWriters map[int64]bool
+}
+
+type waitingFor struct {
+	lock  *goetheLock
+	write bool
+}
+
+func newDeadlockDetector() *deadlockDetector {
+	return &deadlockDetector{
+		holders: make(map[*goetheLock]*lockHolders),
+		waiting: make(map[int64]*waitingFor),
+	}
+}
+
+func (dd *deadlockDetector) enable(failWaiter bool, handler func(*DeadlockError)) {
+	dd.mux.Lock()
+	defer dd.mux.Unlock()
+
+	dd.failWaiter = failWaiter
+	dd.handler = handler
+
+	atomic.StoreInt32(&dd.enabled, 1)
+}
+
+func (dd *deadlockDetector) disable() {
+	dd.mux.Lock()
+	defer dd.mux.Unlock()
+
+	atomic.StoreInt32(&dd.enabled, 0)
+
+	dd.holders = make(map[*goetheLock]*lockHolders)
+	dd.waiting = make(map[int64]*waitingFor)
+}
+
+func (dd *deadlockDetector) isEnabled() bool {
+	return atomic.LoadInt32(&dd.enabled) != 0
+}
+
+// getHolders must have mutex held
+func (dd *deadlockDetector) getHolders(lock *goetheLock) *lockHolders {
+	retVal, found := dd.holders[lock]
+	if !found {
+		retVal = &lockHolders{
+			writer:         -2,
+			readers:        make(map[int64]bool),
+			waitingWriters: make(map[int64]bool),
+		}
+
+		dd.holders[lock] = retVal
+	}
+
+	return retVal
+}
+
+// removeIfUnused must have mutex held
+func (dd *deadlockDetector) removeIfUnused(lock *goetheLock, holders *lockHolders) {
+	if holders.writer < 0 && len(holders.readers) == 0 && len(holders.waitingWriters) == 0 {
+		delete(dd.holders, lock)
+	}
+}
+
+// acquired is called when the thread goes from not holding the lock to holding it
+func (dd *deadlockDetector) acquired(lock *goetheLock, tid int64, write bool) {
+	if !dd.isEnabled() {
+		return
+	}
+
+	dd.mux.Lock()
+	defer dd.mux.Unlock()
+
+	holders := dd.getHolders(lock)
+	if write {
+		holders.writer = tid
+	} else {
+		holders.readers[tid] = true
+	}
+}
+
+// released is called when the thread no longer holds the lock at all
+func (dd *deadlockDetector) released(lock *goetheLock, tid int64, write bool) {
+	if !dd.isEnabled() {
+		return
+	}
+
+	dd.mux.Lock()
+	defer dd.mux.Unlock()
+
+	holders, found := dd.holders[lock]
+	if !found {
+		return
+	}
+
+	if write {
+		if holders.writer == tid {
+			holders.writer = -2
+		}
+	} else {
+		delete(holders.readers, tid)
+	}
+
+	dd.removeIfUnused(lock, holders)
+}
+
+// startWaiting is called when the thread is about to wait for the lock.  If the
+// wait completes a cycle of waiting threads the handler is told and, if the detector
+// is set to fail the waiter, the error is returned and the thread is not recorded
+// as waiting
+func (dd *deadlockDetector) startWaiting(lock *goetheLock, tid int64, write bool) *DeadlockError {
+	if !dd.isEnabled() {
+		return nil
+	}
+
+	dd.mux.Lock()
+
+	dd.waiting[tid] = &waitingFor{
+		lock:  lock,
+		write: write,
+	}
+
+	if write {
+		dd.getHolders(lock).waitingWriters[tid] = true
+	}
+
+	deadlock := dd.findCycle(tid)
+	failWaiter := dd.failWaiter
+	handler := dd.handler
+
+	if deadlock != nil && failWaiter {
+		dd.stopWaitingLocked(tid)
+	}
+
+	dd.mux.Unlock()
+
+	if deadlock == nil {
+		return nil
+	}
+
+	if handler != nil {
+		go handler(deadlock)
+	}
+
+	if failWaiter {
+		return deadlock
+	}
+
+	return nil
+}
+
+// stopWaiting is called when the thread is no longer waiting for the lock,
+// whether or not it got the lock
+func (dd *deadlockDetector) stopWaiting(tid int64) {
+	if !dd.isEnabled() {
+		return
+	}
+
+	dd.mux.Lock()
+	defer dd.mux.Unlock()
+
+	dd.stopWaitingLocked(tid)
+}
+
+// stopWaitingLocked must have mutex held
+func (dd *deadlockDetector) stopWaitingLocked(tid int64) {
+	waiting, found := dd.waiting[tid]
+	if !found {
+		return
+	}
+
+	delete(dd.waiting, tid)
+
+	if waiting.write {
+		holders, found := dd.holders[waiting.lock]
+		if found {
+			delete(holders.waitingWriters, tid)
+			dd.removeIfUnused(waiting.lock, holders)
+		}
+	}
+}
+
+// blockers returns the threads the given waiting thread is waiting on.  A writer
+// waits for the writer and all of the readers.  A reader waits for the writer and
+// any waiting writers since waiting writers are given preference.  Must have mutex held
+func (dd *deadlockDetector) blockers(tid int64, waiting *waitingFor) []int64 {
+	holders, found := dd.holders[waiting.lock]
+	if !found {
+		return nil
+	}
+
+	retVal := make([]int64, 0)
+	if holders.writer >= 0 && holders.writer != tid {
+		retVal = append(retVal, holders.writer)
+	}
+
+	others := holders.waitingWriters
+	if waiting.write {
+		others = holders.readers
+	}
+
+	for other := range others {
+		if other != tid {
+			retVal = append(retVal, other)
+		}
+	}
+
+	return retVal
+}
+
+// findCycle does a depth first search of the wait-for graph from the given thread,
+// returning the cycle that leads back to it if there is one.  Must have mutex held
+func (dd *deadlockDetector) findCycle(start int64) *DeadlockError {
+	visited := make(map[int64]bool)
+	threads := make([]int64, 0)
+	locks := make([]Lock, 0)
+
+	var search func(int64) bool
+	search = func(tid int64) bool {
+		waiting, found := dd.waiting[tid]
+		if !found {
+			return false
+		}
+
+		visited[tid] = true
+		threads = append(threads, tid)
+		locks = append(locks, waiting.lock)
+
+		for _, blocker := range dd.blockers(tid, waiting) {
+			if blocker == start {
+				return true
+			}
+
+			if !visited[blocker] && search(blocker) {
+				return true
+			}
+		}
+
+		threads = threads[:len(threads)-1]
+		locks = locks[:len(locks)-1]
+
+		return false
+	}
+
+	if !search(start) {
+		return nil
+	}
+
+	return &DeadlockError{
+		Threads: threads,
+		Locks:   locks,
+	}
+}
+
+func (de *DeadlockError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString("deadlock detected: ")
+	for index, tid := range de.Threads {
+		if index > 0 {
+			sb.WriteString(", ")
+		}
+
+		next := de.Threads[(index+1)%len(de.Threads)]
+		fmt.Fprintf(&sb, "thread %d waits for %s blocked by thread %d", tid, describeLock(de.Locks[index]), next)
+	}
+
+	return sb.String()
+}
+
+// Is returns true if the target is ErrDeadlock
+func (de *DeadlockError) Is(target error) bool {
+	return target == ErrDeadlock
+}
+
+func describeLock(lock Lock) string {
+	if name := lock.GetName(); name != "" {
+		return fmt.Sprintf("lock %s", name)
+	}
+
+	if gl, ok := lock.(*goetheLock); ok {
+		return fmt.Sprintf("lock %d", gl.id)
+	}
+
+	return "lock"
+}
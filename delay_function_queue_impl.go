@@ -0,0 +1,324 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type delayFunctionQueue struct {
+	mux     sync.Mutex
+	cond    *sync.Cond
+	changer func(queue FunctionQueue)
+
+	capacity uint32
+
+	// queue is sorted by due time, and by the order of
+	// enqueue for entries that are due at the same time
+	queue []*delayedEntry
+
+	// timer goes off when the earliest entry that is
+	// not yet due becomes due, timerDue is when that is
+	timer    *time.Timer
+	timerDue time.Time
+}
+
+type delayedEntry struct {
+	due        time.Time
+	descriptor *FunctionDescriptor
+}
+
+// NewDelayFunctionQueue creates a new function queue with the given capacity
+// whose functions are not returned before the time they are due
+func NewDelayFunctionQueue(userCapacity uint32) DelayFunctionQueue {
+	retVal := &delayFunctionQueue{
+		capacity: userCapacity,
+		queue:    make([]*delayedEntry, 0),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal
+}
+
+// Enqueue queues a function that is due immediately.  Returns
+// ErrAtCapacity if the queue is currently at capacity
+func (dq *delayFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	return dq.EnqueueAt(time.Now(), userCall, args...)
+}
+
+// EnqueueAfter queues a function that is due after the given duration.
+// Returns ErrAtCapacity if the queue is currently at capacity
+func (dq *delayFunctionQueue) EnqueueAfter(delay time.Duration, userCall interface{}, args ...interface{}) error {
+	if delay < 0 {
+		return ErrIllegalDuration
+	}
+
+	return dq.EnqueueAt(time.Now().Add(delay), userCall, args...)
+}
+
+// EnqueueAt queues a function that is due at the given time.  Returns
+// ErrAtCapacity if the queue is currently at capacity
+func (dq *delayFunctionQueue) EnqueueAt(due time.Time, userCall interface{}, args ...interface{}) error {
+	if userCall == nil {
+		return nil
+	}
+
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	if uint32(len(dq.queue)) >= dq.capacity {
+		return ErrAtCapacity
+	}
+
+	descriptor := &FunctionDescriptor{
+		UserCall:     userCall,
+		Args:         make([]interface{}, len(args)),
+		EnqueuedTime: time.Now(),
+	}
+
+	for index, arg := range args {
+		descriptor.Args[index] = arg
+	}
+
+	index := sort.Search(len(dq.queue), func(i int) bool {
+		return dq.queue[i].due.After(due)
+	})
+
+	dq.queue = append(dq.queue, nil)
+	copy(dq.queue[index+1:], dq.queue[index:])
+	dq.queue[index] = &delayedEntry{
+		due:        due,
+		descriptor: descriptor,
+	}
+
+	if dq.isDueLocked() {
+		dq.cond.Broadcast()
+	}
+
+	dq.armLocked()
+
+	if dq.changer != nil {
+		go dq.changer(dq)
+	}
+
+	return nil
+}
+
+// Dequeue returns the function that has been due the longest, waiting the
+// given duration for one to become due.  If no function is due within the
+// given duration the error returned will be ErrEmptyQueue
+func (dq *delayFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	if duration < 0 {
+		duration = 0
+	}
+
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	if !waitFor(dq.cond, duration, dq.isDueLocked) {
+		return nil, ErrEmptyQueue
+	}
+
+	return dq.dequeueLocked(), nil
+}
+
+// DequeueContext returns the function that has been due the longest,
+// waiting until one is due or the context is done.  If the context is done
+// before a function is due the error of the context is returned
+func (dq *delayFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	if !dq.isDueLocked() {
+		closer := broadcastOnDone(ctx, dq.cond)
+		defer closer.Close()
+
+		for !dq.isDueLocked() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			dq.cond.Wait()
+		}
+	}
+
+	return dq.dequeueLocked(), nil
+}
+
+// RemoveAll removes every function from the queue, whether or not it
+// is due, and returns them in the order they are due
+func (dq *delayFunctionQueue) RemoveAll() []*FunctionDescriptor {
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	retVal := make([]*FunctionDescriptor, len(dq.queue))
+	for index, entry := range dq.queue {
+		retVal[index] = entry.descriptor
+	}
+
+	dq.queue = make([]*delayedEntry, 0)
+
+	dq.disarmLocked()
+	dq.cond.Broadcast()
+
+	if len(retVal) > 0 && dq.changer != nil {
+		go dq.changer(dq)
+	}
+
+	return retVal
+}
+
+// isDueLocked returns true if the first entry is due.  Must have mutex held
+func (dq *delayFunctionQueue) isDueLocked() bool {
+	return len(dq.queue) > 0 && !dq.queue[0].due.After(time.Now())
+}
+
+// dueCountLocked returns the number of entries that are due.  Must have mutex held
+func (dq *delayFunctionQueue) dueCountLocked() int {
+	now := time.Now()
+
+	return sort.Search(len(dq.queue), func(i int) bool {
+		return dq.queue[i].due.After(now)
+	})
+}
+
+// dequeueLocked must have mutex held and the first entry must be due
+func (dq *delayFunctionQueue) dequeueLocked() *FunctionDescriptor {
+	retVal := dq.queue[0].descriptor
+
+	dq.queue[0] = nil
+	dq.queue = dq.queue[1:]
+
+	dq.armLocked()
+
+	if dq.changer != nil {
+		go dq.changer(dq)
+	}
+
+	return retVal
+}
+
+// armLocked sets the timer to go off when the earliest entry that
+// is not yet due becomes due.  Must have mutex held
+func (dq *delayFunctionQueue) armLocked() {
+	dueCount := dq.dueCountLocked()
+	if dueCount >= len(dq.queue) {
+		dq.disarmLocked()
+		return
+	}
+
+	nextDue := dq.queue[dueCount].due
+	if dq.timer != nil && dq.timerDue.Equal(nextDue) {
+		return
+	}
+
+	dq.disarmLocked()
+
+	dq.timerDue = nextDue
+	dq.timer = time.AfterFunc(time.Until(nextDue), dq.becameDue)
+}
+
+// disarmLocked must have mutex held
+func (dq *delayFunctionQueue) disarmLocked() {
+	if dq.timer == nil {
+		return
+	}
+
+	dq.timer.Stop()
+	dq.timer = nil
+	dq.timerDue = time.Time{}
+}
+
+// becameDue is called by the timer when an entry becomes due
+func (dq *delayFunctionQueue) becameDue() {
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	dq.timer = nil
+	dq.timerDue = time.Time{}
+
+	dq.cond.Broadcast()
+
+	dq.armLocked()
+
+	if dq.changer != nil {
+		go dq.changer(dq)
+	}
+}
+
+// GetCapacity gets the capacity of this queue
+func (dq *delayFunctionQueue) GetCapacity() uint32 {
+	return dq.capacity
+}
+
+// GetSize returns the number of functions on the queue that are due
+func (dq *delayFunctionQueue) GetSize() int {
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	return dq.dueCountLocked()
+}
+
+// GetDelayedSize returns the number of functions on the queue that are not yet due
+func (dq *delayFunctionQueue) GetDelayedSize() int {
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	return len(dq.queue) - dq.dueCountLocked()
+}
+
+// IsEmpty returns true if no function on this queue is due
+func (dq *delayFunctionQueue) IsEmpty() bool {
+	return dq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be called whenever an enqueue
+// or dequeue changes the size of queue or a function becomes due
+func (dq *delayFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	dq.mux.Lock()
+	defer dq.mux.Unlock()
+
+	dq.changer = ch
+}
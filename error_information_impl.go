@@ -40,6 +40,8 @@
 
 package goethe
 
+import "fmt"
+
 type errorInformation struct {
 	tid int64
 	err error
@@ -59,3 +61,27 @@ func (ei *errorInformation) GetThreadID() int64 {
 func (ei *errorInformation) GetError() error {
 	return ei.err
 }
+
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func newPanicError(value interface{}, stack []byte) *panicError {
+	return &panicError{
+		value: value,
+		stack: stack,
+	}
+}
+
+func (pe *panicError) Error() string {
+	return fmt.Sprintf("goethe thread panicked: %v", pe.value)
+}
+
+func (pe *panicError) GetPanicValue() interface{} {
+	return pe.value
+}
+
+func (pe *panicError) GetStack() []byte {
+	return pe.stack
+}
@@ -0,0 +1,318 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type fairShareFunctionQueue struct {
+	mux     sync.Mutex
+	cond    *sync.Cond
+	changer func(queue FunctionQueue)
+
+	capacity       uint32
+	tenantCapacity uint32
+	size           int
+
+	tenants map[string]*fairShareTenant
+
+	// ring holds the tenants with queued functions in round-robin order.
+	// The tenant at current has credits functions left in its turn
+	ring    []*fairShareTenant
+	current int
+	credits uint32
+}
+
+type fairShareTenant struct {
+	name       string
+	weight     uint32
+	capacity   uint32
+	configured bool
+	queue      []*FunctionDescriptor
+}
+
+// NewFairShareFunctionQueue creates a new function queue with the given total
+// capacity whose tenants each have the given capacity unless set otherwise
+// with SetTenant.  A tenant capacity of zero means tenants are only limited
+// by the total capacity
+func NewFairShareFunctionQueue(userCapacity uint32, tenantCapacity uint32) FairShareFunctionQueue {
+	retVal := &fairShareFunctionQueue{
+		capacity:       userCapacity,
+		tenantCapacity: tenantCapacity,
+		tenants:        make(map[string]*fairShareTenant),
+		ring:           make([]*fairShareTenant, 0),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal
+}
+
+// Enqueue queues a function to be run in the pool for the DefaultTenant.
+// Returns ErrAtCapacity if the queue is currently at capacity
+func (fq *fairShareFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	return fq.EnqueueForTenant(DefaultTenant, userCall, args...)
+}
+
+// EnqueueForTenant queues a function to be run in the pool for the given tenant.
+// Returns ErrAtCapacity if either the queue or the tenant is at capacity
+func (fq *fairShareFunctionQueue) EnqueueForTenant(tenantName string, userCall interface{}, args ...interface{}) error {
+	if userCall == nil {
+		return nil
+	}
+
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	if uint32(fq.size) >= fq.capacity {
+		return ErrAtCapacity
+	}
+
+	tenant := fq.getTenantLocked(tenantName)
+	if tenant.capacity > 0 && uint32(len(tenant.queue)) >= tenant.capacity {
+		return ErrAtCapacity
+	}
+
+	descriptor := &FunctionDescriptor{
+		UserCall:     userCall,
+		Args:         make([]interface{}, len(args)),
+		EnqueuedTime: time.Now(),
+	}
+
+	for index, arg := range args {
+		descriptor.Args[index] = arg
+	}
+
+	if len(tenant.queue) <= 0 {
+		fq.ring = append(fq.ring, tenant)
+	}
+
+	tenant.queue = append(tenant.queue, descriptor)
+	fq.size++
+
+	fq.cond.Broadcast()
+	if fq.changer != nil {
+		go fq.changer(fq)
+	}
+
+	return nil
+}
+
+// getTenantLocked returns the named tenant, creating it with the default
+// weight and capacity if it does not exist.  Must have mutex held
+func (fq *fairShareFunctionQueue) getTenantLocked(tenantName string) *fairShareTenant {
+	tenant, found := fq.tenants[tenantName]
+	if found {
+		return tenant
+	}
+
+	tenant = &fairShareTenant{
+		name:     tenantName,
+		weight:   1,
+		capacity: fq.tenantCapacity,
+		queue:    make([]*FunctionDescriptor, 0),
+	}
+
+	fq.tenants[tenantName] = tenant
+
+	return tenant
+}
+
+// SetTenant sets the weight and capacity of a tenant
+func (fq *fairShareFunctionQueue) SetTenant(tenantName string, weight uint32, capacity uint32) error {
+	if weight == 0 {
+		return ErrIllegalCount
+	}
+
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	tenant := fq.getTenantLocked(tenantName)
+
+	tenant.weight = weight
+	tenant.capacity = capacity
+	tenant.configured = true
+
+	return nil
+}
+
+// Dequeue returns a function to be run, waiting the given
+// duration.  If there is no message within the given
+// duration return the error returned will be ErrEmptyQueue
+func (fq *fairShareFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	if duration < 0 {
+		duration = 0
+	}
+
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	found := waitFor(fq.cond, duration, func() bool {
+		return fq.size > 0
+	})
+	if !found {
+		return nil, ErrEmptyQueue
+	}
+
+	return fq.dequeueLocked(), nil
+}
+
+// DequeueContext returns a function to be run, waiting until one
+// is available or the context is done.  If the context is done before
+// a function is available the error of the context is returned
+func (fq *fairShareFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	if fq.size <= 0 {
+		closer := broadcastOnDone(ctx, fq.cond)
+		defer closer.Close()
+
+		for fq.size <= 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			fq.cond.Wait()
+		}
+	}
+
+	return fq.dequeueLocked(), nil
+}
+
+// dequeueLocked takes the next function from the tenant whose turn it is.
+// Must have mutex held and the queue must not be empty
+func (fq *fairShareFunctionQueue) dequeueLocked() *FunctionDescriptor {
+	tenant := fq.ring[fq.current]
+	if fq.credits == 0 {
+		// Start of the turn of this tenant
+		fq.credits = tenant.weight
+	}
+
+	retVal := tenant.queue[0]
+	tenant.queue[0] = nil
+	tenant.queue = tenant.queue[1:]
+
+	fq.size--
+	fq.credits--
+
+	if len(tenant.queue) <= 0 {
+		// The next tenant moves into the current position
+		fq.ring = append(fq.ring[:fq.current], fq.ring[fq.current+1:]...)
+		fq.credits = 0
+
+		if !tenant.configured {
+			delete(fq.tenants, tenant.name)
+		}
+	} else if fq.credits == 0 {
+		fq.current++
+	}
+
+	if fq.current >= len(fq.ring) {
+		fq.current = 0
+	}
+
+	if fq.changer != nil {
+		go fq.changer(fq)
+	}
+
+	return retVal
+}
+
+// GetTenantSize returns the number of functions queued for the given tenant
+func (fq *fairShareFunctionQueue) GetTenantSize(tenantName string) int {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	tenant, found := fq.tenants[tenantName]
+	if !found {
+		return 0
+	}
+
+	return len(tenant.queue)
+}
+
+// GetTenants returns the sorted names of the known tenants
+func (fq *fairShareFunctionQueue) GetTenants() []string {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	retVal := make([]string, 0, len(fq.tenants))
+	for name := range fq.tenants {
+		retVal = append(retVal, name)
+	}
+
+	sort.Strings(retVal)
+
+	return retVal
+}
+
+// GetCapacity gets the capacity of this queue
+func (fq *fairShareFunctionQueue) GetCapacity() uint32 {
+	return fq.capacity
+}
+
+// GetSize returns the number of items currently in the queue
+func (fq *fairShareFunctionQueue) GetSize() int {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	return fq.size
+}
+
+// IsEmpty Returns true if this queue is currently empty
+func (fq *fairShareFunctionQueue) IsEmpty() bool {
+	return fq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be
+// called whenever an enqueue or dequeue changes
+// the size of queue
+func (fq *fairShareFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	fq.changer = ch
+}
@@ -0,0 +1,421 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type forkJoinPool struct {
+	parent      *StandardThreadUtilities
+	parallelism int32
+	errorQueue  ErrorQueue
+	panicPolicy PanicPolicy
+
+	// workers is fixed once the pool is created, workersByTid
+	// maps goethe thread ids to the entries of workers
+	workers      []*forkJoinWorker
+	workersByTid sync.Map
+	submissions  *taskDeque
+
+	stealCount uint64
+	idle       int32
+
+	// mux protects the fields below and is only taken when a thread
+	// runs out of work or the pool is shutting down
+	mux        sync.Mutex
+	cond       *sync.Cond
+	running    int32
+	shutdown   bool
+	terminated bool
+}
+
+type forkJoinWorker struct {
+	index int
+	deque *taskDeque
+}
+
+// taskDeque is a deque of tasks.  The owner pushes and pops tasks
+// at the tail and other threads steal tasks from the head
+type taskDeque struct {
+	mux   sync.Mutex
+	tasks []*forkJoinTask
+}
+
+type forkJoinTask struct {
+	*futureImpl
+	pool    *forkJoinPool
+	joiners int32
+}
+
+func newForkJoinPool(par *StandardThreadUtilities, parallelism int32, eq ErrorQueue) (ForkJoinPool, error) {
+	if parallelism < 1 {
+		return nil, fmt.Errorf("parallelism less than one %d", parallelism)
+	}
+
+	retVal := &forkJoinPool{
+		parent:      par,
+		parallelism: parallelism,
+		errorQueue:  eq,
+		panicPolicy: par.GetPanicPolicy(),
+		workers:     make([]*forkJoinWorker, parallelism),
+		submissions: &taskDeque{},
+		running:     parallelism,
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	for lcv := range retVal.workers {
+		retVal.workers[lcv] = &forkJoinWorker{
+			index: lcv,
+			deque: &taskDeque{},
+		}
+	}
+
+	for _, worker := range retVal.workers {
+		par.Go(retVal.workerRunner, worker)
+	}
+
+	return retVal, nil
+}
+
+func (pool *forkJoinPool) Fork(method interface{}, args ...interface{}) (ForkJoinTask, error) {
+	argsAsVals, err := getValues(method, args)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &forkJoinTask{
+		futureImpl: newFuture(method, argsAsVals, pool.errorQueue, pool.panicPolicy),
+		pool:       pool,
+	}
+
+	worker := pool.currentWorker()
+	if worker != nil {
+		worker.deque.push(task)
+	} else {
+		pool.mux.Lock()
+		shutdown := pool.shutdown
+		pool.mux.Unlock()
+
+		if shutdown {
+			return nil, ErrPoolClosed
+		}
+
+		pool.submissions.push(task)
+	}
+
+	pool.signalWork()
+
+	return task, nil
+}
+
+func (pool *forkJoinPool) Invoke(method interface{}, args ...interface{}) ([]interface{}, error) {
+	task, err := pool.Fork(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return task.Join()
+}
+
+// currentWorker returns the worker of the calling thread or nil
+// if the calling thread is not a thread of this pool
+func (pool *forkJoinPool) currentWorker() *forkJoinWorker {
+	raw, found := pool.workersByTid.Load(pool.parent.GetThreadID())
+	if !found {
+		return nil
+	}
+
+	return raw.(*forkJoinWorker)
+}
+
+// signalWork wakes up an idle thread if there are any.  A thread going
+// idle increments idle before looking for work with mux held, so either
+// it sees the new task or this sees it as idle and waits for it to sleep
+func (pool *forkJoinPool) signalWork() {
+	if atomic.LoadInt32(&pool.idle) <= 0 {
+		return
+	}
+
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	pool.cond.Signal()
+}
+
+func (pool *forkJoinPool) workerRunner(worker *forkJoinWorker) {
+	pool.workersByTid.Store(pool.parent.GetThreadID(), worker)
+
+	defer func() {
+		pool.workersByTid.Delete(pool.parent.GetThreadID())
+
+		pool.mux.Lock()
+		defer pool.mux.Unlock()
+
+		pool.running--
+		if pool.running <= 0 {
+			pool.terminated = true
+		}
+
+		pool.cond.Broadcast()
+	}()
+
+	for {
+		task := pool.findTask(worker)
+		if task != nil {
+			pool.runTask(task)
+			continue
+		}
+
+		if !pool.awaitWork() {
+			return
+		}
+	}
+}
+
+// runTask runs the task and wakes up any pool threads joining it
+func (pool *forkJoinPool) runTask(task *forkJoinTask) {
+	task.run()
+
+	if atomic.LoadInt32(&task.joiners) <= 0 {
+		return
+	}
+
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	pool.cond.Broadcast()
+}
+
+// findTask returns the next task the worker should run, or nil if there
+// is no work anywhere in the pool.  The worker's own deque is used first,
+// then the shared submissions and finally the deques of the other workers
+func (pool *forkJoinPool) findTask(worker *forkJoinWorker) *forkJoinTask {
+	if task := worker.deque.pop(); task != nil {
+		return task
+	}
+
+	if task := pool.submissions.steal(); task != nil {
+		return task
+	}
+
+	numWorkers := len(pool.workers)
+	for lcv := 1; lcv < numWorkers; lcv++ {
+		victim := pool.workers[(worker.index+lcv)%numWorkers]
+
+		if task := victim.deque.steal(); task != nil {
+			atomic.AddUint64(&pool.stealCount, 1)
+			return task
+		}
+	}
+
+	return nil
+}
+
+// awaitWork waits until there may be work to do, returning false
+// if the pool has been shut down and there is no work left
+func (pool *forkJoinPool) awaitWork() bool {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	atomic.AddInt32(&pool.idle, 1)
+	defer atomic.AddInt32(&pool.idle, -1)
+
+	for {
+		if pool.GetQueuedTaskCount() > 0 {
+			return true
+		}
+
+		if pool.shutdown {
+			// Wake the other idle threads so they exit too
+			pool.cond.Broadcast()
+			return false
+		}
+
+		pool.cond.Wait()
+	}
+}
+
+// awaitJoin waits until either the task is done or there may
+// be other work to help with while the task is running
+func (pool *forkJoinPool) awaitJoin(task *forkJoinTask) {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	atomic.AddInt32(&pool.idle, 1)
+	defer atomic.AddInt32(&pool.idle, -1)
+
+	atomic.AddInt32(&task.joiners, 1)
+	defer atomic.AddInt32(&task.joiners, -1)
+
+	for !task.IsDone() && pool.GetQueuedTaskCount() <= 0 {
+		pool.cond.Wait()
+	}
+}
+
+func (pool *forkJoinPool) GetParallelism() int32 {
+	return pool.parallelism
+}
+
+func (pool *forkJoinPool) GetQueuedTaskCount() int {
+	retVal := pool.submissions.size()
+	for _, worker := range pool.workers {
+		retVal += worker.deque.size()
+	}
+
+	return retVal
+}
+
+func (pool *forkJoinPool) GetStealCount() uint64 {
+	return atomic.LoadUint64(&pool.stealCount)
+}
+
+func (pool *forkJoinPool) GetErrorQueue() ErrorQueue {
+	return pool.errorQueue
+}
+
+func (pool *forkJoinPool) Shutdown() {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	pool.shutdown = true
+	pool.cond.Broadcast()
+}
+
+func (pool *forkJoinPool) IsShutdown() bool {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	return pool.shutdown
+}
+
+func (pool *forkJoinPool) AwaitTermination(d time.Duration) (bool, error) {
+	if d < -1 {
+		return false, ErrIllegalDuration
+	}
+
+	if pool.currentWorker() != nil {
+		// Would wait for itself forever
+		return false, ErrNotCalledOnCorrectThread
+	}
+
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	return waitFor(pool.cond, d, func() bool {
+		return pool.terminated
+	}), nil
+}
+
+func (pool *forkJoinPool) IsTerminated() bool {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	return pool.terminated
+}
+
+func (task *forkJoinTask) Join() ([]interface{}, error) {
+	worker := task.pool.currentWorker()
+	if worker != nil {
+		// Help with the work of the pool until the task is done.  If there
+		// is no work left anywhere the task is running on another thread
+		for !task.IsDone() {
+			next := task.pool.findTask(worker)
+			if next == nil {
+				task.pool.awaitJoin(task)
+				continue
+			}
+
+			task.pool.runTask(next)
+		}
+	}
+
+	return task.Get(-1)
+}
+
+func (deque *taskDeque) push(task *forkJoinTask) {
+	deque.mux.Lock()
+	defer deque.mux.Unlock()
+
+	deque.tasks = append(deque.tasks, task)
+}
+
+func (deque *taskDeque) pop() *forkJoinTask {
+	deque.mux.Lock()
+	defer deque.mux.Unlock()
+
+	last := len(deque.tasks) - 1
+	if last < 0 {
+		return nil
+	}
+
+	retVal := deque.tasks[last]
+	deque.tasks[last] = nil
+	deque.tasks = deque.tasks[:last]
+
+	return retVal
+}
+
+func (deque *taskDeque) steal() *forkJoinTask {
+	deque.mux.Lock()
+	defer deque.mux.Unlock()
+
+	if len(deque.tasks) == 0 {
+		return nil
+	}
+
+	retVal := deque.tasks[0]
+	deque.tasks[0] = nil
+	deque.tasks = deque.tasks[1:]
+
+	return retVal
+}
+
+func (deque *taskDeque) size() int {
+	deque.mux.Lock()
+	defer deque.mux.Unlock()
+
+	return len(deque.tasks)
+}
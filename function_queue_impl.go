@@ -41,6 +41,7 @@
 package goethe
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -85,9 +86,70 @@ func (fq *FunctionQueueImpl) Enqueue(userCall interface{}, args ...interface{})
 		return ErrAtCapacity
 	}
 
+	fq.enqueueLocked(userCall, args)
+
+	return nil
+}
+
+// EnqueueWait queues a function to be run in the pool, waiting at most
+// the given duration for the queue to have room.  A duration of -1 waits
+// forever.  Returns ErrAtCapacity if the queue did not have room in time
+func (fq *FunctionQueueImpl) EnqueueWait(d time.Duration, userCall interface{}, args ...interface{}) error {
+	if d < -1 {
+		return ErrIllegalDuration
+	}
+
+	if userCall == nil {
+		return nil
+	}
+
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	hasRoom := waitFor(fq.cond, d, func() bool {
+		return uint32(len(fq.queue)) < fq.capacity
+	})
+	if !hasRoom {
+		return ErrAtCapacity
+	}
+
+	fq.enqueueLocked(userCall, args)
+
+	return nil
+}
+
+// EnqueueAll queues all of the given functions or none of them.  Returns
+// ErrAtCapacity if the queue does not have room for all of them
+func (fq *FunctionQueueImpl) EnqueueAll(descriptors []*FunctionDescriptor) error {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	count := 0
+	for _, descriptor := range descriptors {
+		if descriptor != nil && descriptor.UserCall != nil {
+			count++
+		}
+	}
+
+	if uint64(len(fq.queue))+uint64(count) > uint64(fq.capacity) {
+		return ErrAtCapacity
+	}
+
+	for _, descriptor := range descriptors {
+		if descriptor != nil && descriptor.UserCall != nil {
+			fq.enqueueLocked(descriptor.UserCall, descriptor.Args)
+		}
+	}
+
+	return nil
+}
+
+// enqueueLocked must have mutex held and the queue must have room
+func (fq *FunctionQueueImpl) enqueueLocked(userCall interface{}, args []interface{}) {
 	descriptor := &FunctionDescriptor{
-		UserCall: userCall,
-		Args:     make([]interface{}, len(args)),
+		UserCall:     userCall,
+		Args:         make([]interface{}, len(args)),
+		EnqueuedTime: time.Now(),
 	}
 
 	for index, arg := range args {
@@ -100,8 +162,6 @@ func (fq *FunctionQueueImpl) Enqueue(userCall interface{}, args ...interface{})
 	if fq.changer != nil {
 		go fq.changer(fq)
 	}
-
-	return nil
 }
 
 // Dequeue returns a function to be run, waiting the given
@@ -130,14 +190,84 @@ func (fq *FunctionQueueImpl) Dequeue(duration time.Duration) (*FunctionDescripto
 		return nil, ErrEmptyQueue
 	}
 
+	return fq.dequeueLocked(), nil
+}
+
+// DequeueContext returns a function to be run, waiting until one
+// is available or the context is done.  If the context is done before
+// a function is available the error of the context is returned
+func (fq *FunctionQueueImpl) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	if len(fq.queue) <= 0 {
+		closer := broadcastOnDone(ctx, fq.cond)
+		defer closer.Close()
+
+		for len(fq.queue) <= 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			fq.cond.Wait()
+		}
+	}
+
+	return fq.dequeueLocked(), nil
+}
+
+// DequeueBatch returns up to max functions, waiting at most the given
+// duration for at least one.  A duration of -1 waits forever.  If there
+// are no functions in time the error returned will be ErrEmptyQueue
+func (fq *FunctionQueueImpl) DequeueBatch(max int, d time.Duration) ([]*FunctionDescriptor, error) {
+	if max < 1 {
+		return nil, ErrIllegalCount
+	}
+	if d < -1 {
+		return nil, ErrIllegalDuration
+	}
+
+	fq.mux.Lock()
+	defer fq.mux.Unlock()
+
+	found := waitFor(fq.cond, d, func() bool {
+		return len(fq.queue) > 0
+	})
+	if !found {
+		return nil, ErrEmptyQueue
+	}
+
+	if max > len(fq.queue) {
+		max = len(fq.queue)
+	}
+
+	retVal := make([]*FunctionDescriptor, max)
+	copy(retVal, fq.queue)
+	fq.queue = fq.queue[max:]
+
+	fq.dequeuedLocked()
+
+	return retVal, nil
+}
+
+// dequeueLocked must have mutex held and the queue must not be empty
+func (fq *FunctionQueueImpl) dequeueLocked() *FunctionDescriptor {
 	retVal := fq.queue[0]
 	fq.queue = fq.queue[1:]
 
+	fq.dequeuedLocked()
+
+	return retVal
+}
+
+// dequeuedLocked wakes up anyone waiting for room after functions have
+// been removed from the queue.  Must have mutex held
+func (fq *FunctionQueueImpl) dequeuedLocked() {
+	fq.cond.Broadcast()
+
 	if fq.changer != nil {
 		go fq.changer(fq)
 	}
-
-	return retVal, nil
 }
 
 // GetCapacity gets the capacity of this queue
@@ -0,0 +1,261 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// states of a future
+const (
+	futurePending = iota
+	futureRunning
+	futureDone
+	futureCancelled
+)
+
+type futureImpl struct {
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	method     interface{}
+	args       []reflect.Value
+	errorQueue ErrorQueue
+	policy     PanicPolicy
+
+	ctx       context.Context
+	ctxCloser io.Closer
+
+	tid     int64
+	state   int
+	results []interface{}
+	err     error
+
+	queued, started, finished time.Time
+}
+
+func newFuture(method interface{}, args []reflect.Value, errorQueue ErrorQueue, policy PanicPolicy) *futureImpl {
+	retVal := &futureImpl{
+		method:     method,
+		args:       args,
+		errorQueue: errorQueue,
+		policy:     policy,
+		tid:        -1,
+		queued:     time.Now(),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal
+}
+
+// watchContext cancels this future if the context is done before the
+// function starts.  While running the function the context is made
+// available with GetContext.  Must be called before the future is run
+func (future *futureImpl) watchContext(ctx context.Context) {
+	future.ctx = ctx
+	future.ctxCloser = afterDone(ctx, func() {
+		future.Cancel()
+	})
+}
+
+// run must be called on the goethe thread that is to run the
+// user method.  It does nothing if the future has been cancelled
+func (future *futureImpl) run() {
+	if !future.start(GetGoethe().GetThreadID()) {
+		return
+	}
+
+	if future.ctx != nil {
+		// The thread may be the caller of Submit, which has its own context
+		previous := globalGoethe.swapThreadContext(future.ctx)
+		defer globalGoethe.setThreadContext(previous)
+	}
+
+	results, err := invoke(future.method, future.args, future.errorQueue, future.policy)
+
+	future.complete(results, err)
+}
+
+func (future *futureImpl) start(tid int64) bool {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	if future.state != futurePending {
+		return false
+	}
+
+	if future.ctx != nil {
+		future.ctxCloser.Close()
+
+		if future.ctx.Err() != nil {
+			future.cancelLocked()
+			return false
+		}
+	}
+
+	future.state = futureRunning
+	future.tid = tid
+	future.started = time.Now()
+
+	return true
+}
+
+func (future *futureImpl) complete(results []interface{}, err error) {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	future.results = results
+	future.err = err
+	future.state = futureDone
+	future.finished = time.Now()
+
+	future.cond.Broadcast()
+}
+
+func (future *futureImpl) Get(d time.Duration) ([]interface{}, error) {
+	if d < -1 {
+		return nil, ErrIllegalDuration
+	}
+
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	done := waitFor(future.cond, d, future.isDoneLocked)
+	if !done {
+		return nil, ErrFutureNotDone
+	}
+
+	if future.state == futureCancelled {
+		return nil, ErrFutureCancelled
+	}
+
+	return future.results, future.err
+}
+
+// isDoneLocked must have mutex held
+func (future *futureImpl) isDoneLocked() bool {
+	return future.state == futureDone || future.state == futureCancelled
+}
+
+func (future *futureImpl) IsDone() bool {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.isDoneLocked()
+}
+
+func (future *futureImpl) Cancel() bool {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	if future.state == futureCancelled {
+		return true
+	}
+	if future.state != futurePending {
+		return false
+	}
+
+	future.cancelLocked()
+
+	return true
+}
+
+// cancelLocked must have mutex held
+func (future *futureImpl) cancelLocked() {
+	future.state = futureCancelled
+
+	if future.ctxCloser != nil {
+		future.ctxCloser.Close()
+	}
+
+	future.cond.Broadcast()
+}
+
+func (future *futureImpl) IsCancelled() bool {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.state == futureCancelled
+}
+
+func (future *futureImpl) GetThreadID() int64 {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.tid
+}
+
+func (future *futureImpl) GetResults() []interface{} {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.results
+}
+
+func (future *futureImpl) GetError() error {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.err
+}
+
+func (future *futureImpl) GetQueuedTime() time.Time {
+	return future.queued
+}
+
+func (future *futureImpl) GetStartedTime() time.Time {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.started
+}
+
+func (future *futureImpl) GetFinishedTime() time.Time {
+	future.mux.Lock()
+	defer future.mux.Unlock()
+
+	return future.finished
+}
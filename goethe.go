@@ -46,6 +46,7 @@
 package goethe
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -63,6 +64,115 @@ type Timer interface {
 	GetErrorQueue() ErrorQueue
 }
 
+// Future represents the result of a function that was run on a goethe
+// thread.  All of the values returned by the function are available
+// from the Future once the function has completed
+type Future interface {
+	// Get waits the given duration for the function to complete and returns all of
+	// the values returned by the function along with the first non-nil error returned
+	// by the function.  If the duration is zero then it will return immediately.  If
+	// the duration is -1 it will wait forever.  Other negative values will cause an
+	// error to return.  If the function did not complete in the given duration
+	// ErrFutureNotDone is returned, and if the future was cancelled before the function
+	// ran ErrFutureCancelled is returned
+	Get(time.Duration) ([]interface{}, error)
+
+	// IsDone returns true if the function has completed or if this future was cancelled
+	IsDone() bool
+
+	// Cancel keeps the function from being run if it has not yet started.  Returns
+	// true if the function will never be run and false if the function has already
+	// started or completed
+	Cancel() bool
+
+	// IsCancelled returns true if this future was cancelled before the function ran
+	IsCancelled() bool
+
+	// GetThreadID returns the id of the goethe thread the function was run on, or
+	// -1 if the function has not yet been given a thread
+	GetThreadID() int64
+
+	// GetResults returns all of the values returned by the function, or nil
+	// if the function has not completed
+	GetResults() []interface{}
+
+	// GetError returns the first non-nil error returned by the function, or nil
+	// if the function has not completed or returned no error
+	GetError() error
+
+	// GetQueuedTime returns the time at which the function was given to the system
+	GetQueuedTime() time.Time
+
+	// GetStartedTime returns the time at which the function started running, or the
+	// zero time if it has not yet started
+	GetStartedTime() time.Time
+
+	// GetFinishedTime returns the time at which the function completed, or the
+	// zero time if it has not yet completed
+	GetFinishedTime() time.Time
+}
+
+// ForkJoinTask is a Future for a function given to a ForkJoinPool
+type ForkJoinTask interface {
+	Future
+
+	// Join waits for the task to complete and returns all of the values returned by
+	// the function along with the first non-nil error returned by the function.
+	// When called from a thread of the pool the thread runs other tasks of the pool
+	// while it waits.  Returns ErrFutureCancelled if the task was cancelled before it ran
+	Join() ([]interface{}, error)
+}
+
+// ForkJoinPool runs recursive divide-and-conquer tasks.  Each thread of the
+// pool has its own deque of tasks.  Tasks forked from a thread of the pool
+// go on the deque of that thread, which runs them newest first.  Idle threads
+// steal the oldest tasks from the deques of the other threads
+type ForkJoinPool interface {
+	// Fork schedules the function with the given arguments and returns the task
+	// for it.  When called from a thread of this pool the task is put on the deque
+	// of the calling thread, otherwise it is put on a queue shared by the threads of
+	// this pool.  Returns ErrPoolClosed if the pool has been shut down and the calling
+	// thread is not a thread of this pool.  Threads of this pool can still fork after
+	// Shutdown so that running tasks can finish, see Shutdown
+	Fork(interface{}, ...interface{}) (ForkJoinTask, error)
+
+	// Invoke forks the function and joins it, returning the values returned
+	// by the function along with the first non-nil error
+	Invoke(interface{}, ...interface{}) ([]interface{}, error)
+
+	// GetParallelism returns the number of threads in this pool
+	GetParallelism() int32
+
+	// GetQueuedTaskCount returns the number of tasks waiting to be run
+	GetQueuedTaskCount() int
+
+	// GetStealCount returns the number of tasks a thread of this pool has taken
+	// from the deque of another thread
+	GetStealCount() uint64
+
+	// GetErrorQueue returns the error queue associated with this pool
+	GetErrorQueue() ErrorQueue
+
+	// Shutdown stops this pool from accepting new tasks from threads outside
+	// of the pool.  Tasks already given to the pool, and any tasks they fork, are
+	// run before the threads of the pool exit.  Forks from the threads of the pool
+	// are never rejected, so a task that keeps forking keeps the pool running
+	Shutdown()
+
+	// IsShutdown returns true if Shutdown has been called
+	IsShutdown() bool
+
+	// AwaitTermination waits the given duration for the pool to be shut down and
+	// all of its threads to exit.  A duration of -1 waits forever.  Returns true
+	// if the pool has terminated.  Returns ErrNotCalledOnCorrectThread if called
+	// from a thread of this pool and ErrIllegalDuration if the duration is less than -1
+	AwaitTermination(time.Duration) (bool, error)
+
+	// IsTerminated returns true if the pool has been shut down and all of its
+	// threads have exited
+	IsTerminated() bool
+}
+
 // ThreadLocal is returned from GetThreadLocal, a different
 // one for each goethe thread
 type ThreadLocal interface {
@@ -88,6 +198,37 @@ type ThreadUtilities interface {
 	// an error is returned.  The thread id is also returned
 	Go(interface{}, ...interface{}) (int64, error)
 
+	// GoWithFuture is the same as Go except that it returns a Future
+	// which can be used to wait for the function to complete and to get
+	// all of the values returned by the function.  The thread id can
+	// be found on the returned Future
+	GoWithFuture(interface{}, ...interface{}) (Future, error)
+
+	// GoContext is the same as Go except that the given context can be retrieved
+	// with GetContext from inside the goethe thread running the function
+	GoContext(context.Context, interface{}, ...interface{}) (int64, error)
+
+	// SetPanicPolicy sets the policy used when user code running on a goethe thread
+	// started by this ThreadUtilities (including timer threads) panics.  The default
+	// policy is PanicRecoverAndLog.  Pools have their own policy, which is initialized
+	// from this policy when the pool is created
+	SetPanicPolicy(PanicPolicy)
+
+	// GetPanicPolicy returns the policy used when user code running on a goethe
+	// thread panics
+	GetPanicPolicy() PanicPolicy
+
+	// SetPanicHandler sets a function that is called with every panic recovered on
+	// any goethe thread.  The error of the ErrorInformation given to the handler
+	// will be a PanicError.  The handler may be nil
+	SetPanicHandler(func(ErrorInformation))
+
+	// GetContext returns the context associated with the current goethe thread
+	// by GoContext or Pool.SubmitContext.  If there is no such context then
+	// context.Background() is returned.  Will return ErrNotGoetheThread if
+	// called from a non-goethe thread
+	GetContext() (context.Context, error)
+
 	// GetthreadID Gets the current threadID.  Returns -1
 	// if this is not a goethe thread.  Thread ids start at 10
 	// as thread ids 0 through 9 are reserved for future use
@@ -96,6 +237,60 @@ type ThreadUtilities interface {
 	// NewGoetheLock Creates a new goethe lock
 	NewGoetheLock() Lock
 
+	// NewNamedGoetheLock creates a new goethe lock with the given name.  The
+	// name is returned by Lock.GetName and is used when describing deadlocks
+	NewNamedGoetheLock(string) Lock
+
+	// NewFairGoetheLock creates a new goethe lock with the given name (which may
+	// be empty) that is granted in the order threads asked for it.  Consecutive
+	// readers waiting for the lock are let in together.  A thread that already
+	// holds the read lock may read lock again or upgrade without waiting its turn
+	NewFairGoetheLock(string) Lock
+
+	// NewSemaphore creates a counting semaphore with the given number of permits.
+	// The semaphore can be used from any thread.  Returns ErrIllegalPermits if
+	// permits is negative
+	NewSemaphore(permits int32) (Semaphore, error)
+
+	// NewTrackedSemaphore creates a counting semaphore with the given number of permits
+	// that keeps track of how many permits each goethe thread holds.  If a goethe thread
+	// ends while holding permits an ErrPermitsLeaked error is placed on the given
+	// ErrorQueue (which may be nil).  Leaked permits are not given back to the semaphore.
+	// A tracked semaphore may only be used from goethe threads.  Returns ErrIllegalPermits
+	// if permits is negative
+	NewTrackedSemaphore(permits int32, leaks ErrorQueue) (Semaphore, error)
+
+	// NewCountDownLatch creates a latch that opens once CountDown has been called
+	// the given number of times.  Returns ErrIllegalCount if count is negative
+	NewCountDownLatch(count int32) (CountDownLatch, error)
+
+	// NewCyclicBarrier creates a barrier that lets threads through once the given number
+	// of parties have arrived, after which it can be used again.  If action is not nil it
+	// is called by the last thread to arrive, before any thread is let through, with the
+	// thread ids of the threads that arrived.  The action must not use the barrier.
+	// If the action panics the barrier is broken and the panic is handled with the
+	// panic policy, the last thread to arrive gets the PanicError from Await.
+	// Returns ErrIllegalCount if parties is less than one
+	NewCyclicBarrier(parties int32, action func(arrivals []int64)) (CyclicBarrier, error)
+
+	// NewPhaser creates a phaser with the given number of registered parties.  If
+	// onAdvance is not nil it is called with the phase that is completing and the
+	// thread ids of the threads that arrived in that phase.  onAdvance must not use
+	// the phaser.  Returns ErrIllegalCount if parties is negative
+	NewPhaser(parties int32, onAdvance func(phase int32, arrivals []int64)) (Phaser, error)
+
+	// EnableDeadlockDetection starts tracking which goethe threads hold and
+	// are waiting for goethe locks.  When a thread starts waiting for a lock
+	// and that wait completes a cycle of waiting threads the handler (which
+	// may be nil) is called on its own goroutine with a DeadlockError describing
+	// the cycle.  If failWaiter is true the lock call of the thread that completed
+	// the cycle also stops waiting and returns the DeadlockError.  Only locks
+	// acquired after detection is enabled are tracked
+	EnableDeadlockDetection(failWaiter bool, handler func(*DeadlockError))
+
+	// DisableDeadlockDetection stops tracking goethe locks for deadlocks
+	DisableDeadlockDetection()
+
 	// NewPool creates a new thread pool with the given parameters.  The name is the
 	// name of this pool and may not be empty.  It is an error to try to create more than
 	// one open pool with the same name at the same time.
@@ -119,6 +314,12 @@ type ThreadUtilities interface {
 	// value returned will be false
 	GetPool(string) (Pool, bool)
 
+	// NewForkJoinPool creates and starts a pool of parallelism goethe threads, each
+	// with its own deque of tasks.  Idle threads steal tasks from the other threads
+	// of the pool.  errorQueue may be nil but if not nil any error returned by a task
+	// will be enqueued onto the errorQueue.  parallelism must be at least one
+	NewForkJoinPool(parallelism int32, errorQueue ErrorQueue) (ForkJoinPool, error)
+
 	// EstablishThreadLocal tells the system of the named thread local storage
 	// initialize method and destroy method.  This method can be called on any
 	// thread, including non-goethe threads.  Both the initializer and
@@ -175,6 +376,26 @@ type Pool interface {
 	// removed from the pool)
 	GetIdleDecayDuration() time.Duration
 
+	// SetMinThreads changes the minimum number of threads for this pool.
+	// If the pool is running and has fewer threads than the new minimum
+	// new threads are started immediately.  Returns an error if the minimum
+	// is less than zero or greater than the maximum number of threads
+	SetMinThreads(int32) error
+
+	// SetMaxThreads changes the maximum number of threads for this pool.
+	// If the pool has more threads than the new maximum, idle threads leave
+	// the pool immediately and running threads leave once their current
+	// function returns.  Returns an error if the maximum is less than one or
+	// less than the minimum number of threads
+	SetMaxThreads(int32) error
+
+	// SetIdleDecayDuration changes the duration a thread must be idle before
+	// being removed from the pool.  Idle threads waiting on a FunctionQueue that
+	// implements ContextFunctionQueue start waiting again with the new duration,
+	// other idle threads only use the new duration once their current wait with
+	// the old duration is over.  Returns ErrIllegalDuration if the duration is negative
+	SetIdleDecayDuration(time.Duration) error
+
 	// GetCurrentThreadCount returns the current number of active threads
 	// in this pool
 	GetCurrentThreadCount() int32
@@ -185,6 +406,52 @@ type Pool interface {
 	// GetErrorQueue returns the error queue associated with this pool
 	GetErrorQueue() ErrorQueue
 
+	// SetPanicPolicy sets the policy used when a function run by this pool panics.
+	// A recovered panic is placed on the ErrorQueue of this pool as a PanicError
+	// and the thread continues on to the next function
+	SetPanicPolicy(PanicPolicy)
+
+	// GetPanicPolicy returns the policy used when a function run by this pool panics
+	GetPanicPolicy() PanicPolicy
+
+	// SetRejectionPolicy sets what Submit and SubmitContext do when the FunctionQueue
+	// of this pool is at capacity.  The blockTimeout is how long the RejectBlock policy
+	// waits for room on the FunctionQueue, with -1 waiting forever, and is otherwise
+	// ignored.  Returns ErrIllegalDuration if blockTimeout is less than -1
+	SetRejectionPolicy(policy RejectionPolicy, blockTimeout time.Duration) error
+
+	// GetRejectionPolicy returns the rejection policy of this pool and its block timeout
+	GetRejectionPolicy() (RejectionPolicy, time.Duration)
+
+	// SetBatchSize sets the number of functions a thread of this pool takes from the
+	// FunctionQueue at a time, which can lower the cost of running many small functions.
+	// Batches are only taken if the FunctionQueue is a BatchFunctionQueue.  A thread
+	// runs every function of its batch, even if the pool is shut down while it does so.
+	// The default is one.  Returns ErrIllegalCount if the size is less than one
+	SetBatchSize(int32) error
+
+	// GetBatchSize returns the number of functions a thread of this pool takes
+	// from the FunctionQueue at a time
+	GetBatchSize() int32
+
+	// SetRejectionHandler sets a function that is called with every function rejected
+	// by this pool, as it would have appeared on the FunctionQueue.  With the
+	// RejectDiscardOldest policy this is the function removed from the FunctionQueue.
+	// The handler may be nil
+	SetRejectionHandler(func(*FunctionDescriptor))
+
+	// Submit enqueues the function and its arguments onto the FunctionQueue of this
+	// pool and returns a Future that can be used to find out when and how the function
+	// completed, including the id of the thread it ran on.  Any error returned by the
+	// function will also be placed on the ErrorQueue of this pool.  Returns ErrPoolClosed
+	// if this pool has been closed or any error returned by the FunctionQueue
+	Submit(interface{}, ...interface{}) (Future, error)
+
+	// SubmitContext is the same as Submit except that the returned Future is cancelled
+	// if the context is done before the function starts running.  The context can be
+	// retrieved with GetContext from inside the function while it is running
+	SubmitContext(context.Context, interface{}, ...interface{}) (Future, error)
+
 	// IsClosed returns true if this pool has been closed.  Will remove
 	// this pool from Goethe's map of pools
 	IsClosed() bool
@@ -192,8 +459,95 @@ type Pool interface {
 	// Close closes this pool.  All work remaining will be completed, but
 	// no new work will be accepted.  The system will stop reading from
 	// the FunctionQueue, so any remaining jobs can be found on the function
-	// queue
+	// queue.  Close is the same as Shutdown(false)
 	Close()
+
+	// Shutdown closes this pool so that no new work will be accepted.  Functions
+	// that are currently running will be completed.  If drain is true the threads
+	// of the pool will continue to run the functions remaining on the FunctionQueue
+	// until it is empty, otherwise the remaining jobs will be left on the FunctionQueue.
+	// Shutdown does not wait for the threads of the pool to finish, use
+	// AwaitTermination for that
+	Shutdown(drain bool)
+
+	// ShutdownNow closes this pool without draining the FunctionQueue.  The jobs
+	// remaining on the FunctionQueue are removed and returned, including the jobs
+	// of a DelayFunctionQueue that are not yet due.  Futures of jobs given to
+	// Submit that are returned are cancelled.  Functions that are currently
+	// running will be completed
+	ShutdownNow() []*FunctionDescriptor
+
+	// AwaitTermination waits for the pool to have been shutdown and for all
+	// of the threads of the pool to have finished, waiting at most the given
+	// duration.  A duration of 0 does not wait and a duration of -1 waits forever.
+	// Returns true if the pool terminated.  Returns ErrIllegalDuration if the
+	// duration is less than -1 and ErrNotCalledOnCorrectThread if called from a
+	// thread of this pool.  Threads waiting on a FunctionQueue that does not
+	// implement ContextFunctionQueue are only noticed after the idle decay duration
+	AwaitTermination(time.Duration) (bool, error)
+
+	// IsTerminated returns true if the pool has been shutdown and all of the
+	// threads of the pool have finished
+	IsTerminated() bool
+
+	// GetStatistics returns a snapshot of the statistics of this pool
+	GetStatistics() PoolStatistics
+}
+
+// PoolStatistics is a snapshot of the statistics of a Pool
+type PoolStatistics struct {
+	// ActiveThreads is the number of threads currently running user code
+	ActiveThreads int32
+	// IdleThreads is the number of threads currently waiting on the FunctionQueue
+	IdleThreads int32
+	// PeakThreads is the largest number of threads the pool has had at one time
+	PeakThreads int32
+
+	// CompletedTasks is the number of functions that returned without an error
+	CompletedTasks uint64
+	// FailedTasks is the number of functions that returned an error, panicked or
+	// could not be called with the arguments given
+	FailedTasks uint64
+	// RejectedTasks is the number of functions given to Submit or SubmitContext
+	// that could not be put on the FunctionQueue, including those run by the
+	// RejectCallerRuns policy, plus the number removed from the FunctionQueue by
+	// the RejectDiscardOldest policy
+	RejectedTasks uint64
+	// CancelledTasks is the number of futures taken from the FunctionQueue that
+	// were cancelled, or whose context was done, before their function started.
+	// They are not counted as completed or failed and have no execution time
+	CancelledTasks uint64
+
+	// ThreadsCreated is the number of threads the pool has started
+	ThreadsCreated uint64
+	// ThreadsDecayed is the number of threads that left the pool after being
+	// idle for the idle decay duration
+	ThreadsDecayed uint64
+
+	// QueueWaitTime is how long functions waited on the FunctionQueue before
+	// being run.  Functions whose FunctionDescriptor has no EnqueuedTime are
+	// not counted
+	QueueWaitTime Histogram
+	// ExecutionTime is how long functions took to run
+	ExecutionTime Histogram
+}
+
+// Histogram is a distribution of durations.  Counts[i] is the number of
+// durations less than or equal to Bounds[i] (and greater than Bounds[i-1]).
+// The last entry of Counts, which has no bound, is the number of durations
+// greater than every bound
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+
+	// Count is the total number of durations recorded
+	Count uint64
+	// Sum is the total of all durations recorded
+	Sum time.Duration
+	// Min is the smallest duration recorded
+	Min time.Duration
+	// Max is the largest duration recorded
+	Max time.Duration
 }
 
 // Lock is a reader/writer lock that is a counting lock
@@ -249,6 +603,243 @@ type Lock interface {
 	// return immediately with the answer.  If the duration is -1 it will wait forever.  Other
 	// negative values will cause an error to return
 	TryWriteLock(d time.Duration) (bool, error)
+
+	// ReadLockContext is the same as ReadLock except that it will stop waiting for the
+	// lock if the context is done, in which case the error of the context is returned
+	ReadLockContext(ctx context.Context) error
+
+	// WriteLockContext is the same as WriteLock except that it will stop waiting for the
+	// lock if the context is done, in which case the error of the context is returned
+	WriteLockContext(ctx context.Context) error
+
+	// DowngradeToRead converts every write hold of the calling thread into a read hold
+	// without letting any other writer take the lock.  Each of the new read holds must
+	// be released with ReadUnlock.  Returns ErrWriteLockNotHeld if the calling thread
+	// does not hold the write lock
+	DowngradeToRead() error
+
+	// TryUpgrade acquires the write lock for a thread that already holds the read lock,
+	// waiting until the calling thread is the only reader.  The duration has the same
+	// meaning as it does for TryWriteLock.  The read holds of the calling thread are kept,
+	// and the write hold must be released with WriteUnlock (or turned into another read hold
+	// with DowngradeToRead).  Only one thread may wait to upgrade at a time, any other thread
+	// gets ErrUpgradeInProgress and should release its read lock.  Returns ErrReadLockNotHeld
+	// if the calling thread does not hold the read lock
+	TryUpgrade(d time.Duration) (bool, error)
+
+	// NewCondition returns a new Condition bound to this lock.  The write lock
+	// of this lock must be held to use the Condition
+	NewCondition() Condition
+
+	// GetName returns the name given to NewNamedGoetheLock or the empty string
+	GetName() string
+
+	// IsFair returns true if this lock was created with NewFairGoetheLock
+	IsFair() bool
+
+	// GetWaitStatistics returns how long threads have waited for this lock, keyed
+	// by thread id.  Only threads that had to wait for the lock are included.  Can
+	// be called from non-goethe threads
+	GetWaitStatistics() map[int64]LockWaitStatistics
+
+	// ResetWaitStatistics clears the statistics returned by GetWaitStatistics
+	ResetWaitStatistics()
+
+	// GetWriteOwner returns the thread id of the thread holding the write lock or
+	// -1 if the lock is not held for write.  Can be called from non-goethe threads
+	GetWriteOwner() int64
+
+	// GetReadHolders returns a copy of the read hold counts keyed by thread id.  Can
+	// be called from non-goethe threads
+	GetReadHolders() map[int64]int32
+
+	// GetWaitingWriters returns the ids of the threads waiting for the write lock
+	// in ascending order.  Can be called from non-goethe threads
+	GetWaitingWriters() []int64
+
+	// GetWaitingReaders returns the ids of the threads waiting for the read lock
+	// in ascending order.  Can be called from non-goethe threads
+	GetWaitingReaders() []int64
+
+	// GetWriteHoldCount returns the number of times the calling thread holds the
+	// write lock.  Returns 0 if called from a non-goethe thread
+	GetWriteHoldCount() int32
+
+	// GetReadHoldCount returns the number of times the calling thread holds the
+	// read lock.  Returns 0 if called from a non-goethe thread
+	GetReadHoldCount() int32
+}
+
+// Semaphore is a counting semaphore
+type Semaphore interface {
+	// Acquire takes the given number of permits, waiting until they are available
+	Acquire(n int32) error
+
+	// TryAcquire takes the given number of permits, waiting at most the given duration
+	// for them to become available.  A duration of 0 does not wait and a duration of
+	// -1 waits forever.  Returns true if the permits were taken.  Returns
+	// ErrIllegalDuration if the duration is less than -1
+	TryAcquire(n int32, d time.Duration) (bool, error)
+
+	// Release gives back the given number of permits
+	Release(n int32) error
+
+	// GetAvailablePermits returns the number of permits that can currently be taken
+	GetAvailablePermits() int32
+
+	// GetHeldPermits returns the number of permits held by the calling goethe thread.
+	// Always returns 0 if the semaphore was not created with NewTrackedSemaphore
+	GetHeldPermits() int32
+}
+
+// CountDownLatch lets threads wait until a count reaches zero.  The thread id
+// of every thread that counts down is remembered, with -1 used for non-goethe
+// threads
+type CountDownLatch interface {
+	// CountDown decrements the count, opening the latch when it reaches zero.
+	// Calls made after the count has reached zero have no effect
+	CountDown()
+
+	// Await waits for the count to reach zero, waiting at most the given duration.
+	// A duration of 0 does not wait and a duration of -1 waits forever.  Returns true
+	// if the count reached zero.  Returns ErrIllegalDuration if the duration is
+	// less than -1
+	Await(time.Duration) (bool, error)
+
+	// GetCount returns the current count
+	GetCount() int32
+
+	// GetArrivals returns the thread ids of the threads that have counted down in
+	// the order that they did so
+	GetArrivals() []int64
+}
+
+// CyclicBarrier lets a fixed number of parties wait for each other.  Once the
+// last party arrives every waiting thread is let through and the barrier can be
+// used again.  If a waiting thread times out or the barrier is Reset while threads
+// are waiting the barrier is broken, and stays broken until it is Reset
+type CyclicBarrier interface {
+	// Await arrives at the barrier and waits for the remaining parties, waiting at
+	// most the given duration.  A duration of -1 waits forever.  Returns the thread
+	// ids of every party in the order they arrived, with -1 used for non-goethe threads.
+	// Returns ErrTimeout if the duration passed, in which case the barrier is broken,
+	// ErrBarrierBroken if the barrier is or becomes broken and ErrIllegalDuration if
+	// the duration is less than -1
+	Await(time.Duration) ([]int64, error)
+
+	// Reset breaks the barrier for any threads currently waiting and makes
+	// the barrier usable again
+	Reset()
+
+	// IsBroken returns true if the barrier is broken
+	IsBroken() bool
+
+	// GetParties returns the number of parties needed to trip the barrier
+	GetParties() int32
+
+	// GetNumberWaiting returns the number of parties currently waiting
+	GetNumberWaiting() int32
+}
+
+// Phaser is a reusable barrier with a changing number of registered parties.
+// Each phase completes when every registered party has arrived, at which point
+// the phase number increases.  If the number of registered parties goes to zero
+// when a phase completes the phaser is terminated
+type Phaser interface {
+	// Register adds a party to this phaser, returning the current phase.  Returns
+	// ErrPhaserTerminated if the phaser is terminated
+	Register() (int32, error)
+
+	// Arrive arrives at the current phase without waiting, returning the phase
+	// arrived at.  Returns ErrPhaserNoParties if every registered party has
+	// already arrived and ErrPhaserTerminated if the phaser is terminated
+	Arrive() (int32, error)
+
+	// ArriveAndDeregister removes a party from this phaser without waiting,
+	// returning the current phase.  The party is not counted as an arrival, the
+	// phase completes once the remaining registered parties have arrived
+	ArriveAndDeregister() (int32, error)
+
+	// ArriveAndAwaitAdvance arrives at the current phase and waits at most the given
+	// duration for the phase to complete.  Returns the new phase.  Returns ErrTimeout
+	// if the duration passed, in which case the arrival still counts
+	ArriveAndAwaitAdvance(time.Duration) (int32, error)
+
+	// AwaitAdvance waits at most the given duration for the given phase to complete,
+	// returning the new phase.  Returns immediately if the phaser is already past
+	// the given phase.  Returns ErrTimeout if the duration passed and ErrPhaserTerminated
+	// if the phaser is terminated
+	AwaitAdvance(phase int32, d time.Duration) (int32, error)
+
+	// GetPhase returns the current phase
+	GetPhase() int32
+
+	// GetRegisteredParties returns the number of registered parties
+	GetRegisteredParties() int32
+
+	// GetArrivedParties returns the number of parties that have arrived at the current phase
+	GetArrivedParties() int32
+
+	// GetArrivals returns the thread ids of the threads that have arrived at the current
+	// phase in the order they arrived, with -1 used for non-goethe threads
+	GetArrivals() []int64
+
+	// IsTerminated returns true if this phaser is terminated
+	IsTerminated() bool
+}
+
+// LockWaitStatistics describes how long one thread has waited for a Lock
+type LockWaitStatistics struct {
+	// Waits is the number of times the thread had to wait for the lock
+	Waits uint64
+	// TotalWait is the total time the thread has waited for the lock
+	TotalWait time.Duration
+	// MaxWait is the longest the thread has waited for the lock
+	MaxWait time.Duration
+}
+
+// Condition is a condition variable bound to a goethe Lock.  Unlike
+// sync.Cond it understands that the lock is counting, so all of the write
+// holds of the calling thread are released while waiting and restored
+// when the wait is over.  Signals are not lost, a thread given a signal
+// always wakes up
+type Condition interface {
+	// Await releases every write hold the calling thread has on the lock and waits
+	// for Signal or SignalAll, waiting at most the given duration.  A duration of
+	// -1 waits forever.  Before returning the lock is held again with exactly the
+	// same write hold count, even if the wait timed out.  Returns true if the thread
+	// was signalled.  Returns ErrWriteLockNotHeld if the calling thread does not
+	// hold the write lock, ErrReadLockHeld if the calling thread also holds the read
+	// lock and ErrIllegalDuration if the duration is less than -1.  If deadlock
+	// detection fails the wait to hold the lock again the DeadlockError is returned
+	// and the lock is not held, in which case the caller must not call WriteUnlock
+	Await(time.Duration) (bool, error)
+
+	// Signal wakes up the thread that has been waiting the longest in Await, if any.
+	// Returns ErrWriteLockNotHeld if the calling thread does not hold the write lock
+	Signal() error
+
+	// SignalAll wakes up every thread waiting in Await.  Returns ErrWriteLockNotHeld
+	// if the calling thread does not hold the write lock
+	SignalAll() error
+}
+
+// DeadlockError describes a cycle of goethe threads waiting on goethe locks.
+// Threads[i] is waiting for Locks[i], which is held by (or for a reader, being
+// waited on by a writer) Threads[i+1].  The last thread is waiting for a lock
+// blocked by the first thread.  errors.Is(err, ErrDeadlock) is true for a
+// DeadlockError
+type DeadlockError struct {
+	Threads []int64
+	Locks   []Lock
+}
+
+// RequeueError is returned by the Closer of NewFunctionQueueChannel when a
+// function that was dequeued but never received could not be enqueued again.
+// The function is not on any queue, Descriptor is the only record of it
+type RequeueError struct {
+	Descriptor *FunctionDescriptor
+	Err        error
 }
 
 // FunctionDescriptor describes a function to be called with
@@ -256,6 +847,10 @@ type Lock interface {
 type FunctionDescriptor struct {
 	UserCall interface{}
 	Args     []interface{}
+
+	// EnqueuedTime is when the function was placed on the queue.  It
+	// may be the zero time if the FunctionQueue does not keep track
+	EnqueuedTime time.Time
 }
 
 // FunctionQueue a queue of functions to be enqueued and dequeued
@@ -286,6 +881,188 @@ type FunctionQueue interface {
 	SetStateChangeCallback(func(FunctionQueue))
 }
 
+// ContextFunctionQueue is a FunctionQueue that can also wait for a
+// function to become available until a context is done.  The queue
+// returned by NewBoundedFunctionQueue implements this interface
+type ContextFunctionQueue interface {
+	FunctionQueue
+
+	// DequeueContext returns a function to be run, waiting until one is
+	// available or the context is done.  If the context is done before a
+	// function is available the error of the context is returned
+	DequeueContext(context.Context) (*FunctionDescriptor, error)
+}
+
+// BatchFunctionQueue is a FunctionQueue that can also wait for capacity and
+// move more than one function at a time.  The queue returned by
+// NewBoundedFunctionQueue implements this interface
+type BatchFunctionQueue interface {
+	FunctionQueue
+
+	// EnqueueWait queues a function to be run in the pool, waiting at most the
+	// given duration for the queue to have room.  A duration of -1 waits forever.
+	// Returns ErrAtCapacity if the queue did not have room in time and
+	// ErrIllegalDuration if the duration is less than -1
+	EnqueueWait(d time.Duration, userCall interface{}, args ...interface{}) error
+
+	// EnqueueAll queues all of the given functions or none of them.  Descriptors
+	// with a nil UserCall are ignored.  Returns ErrAtCapacity if the queue does not
+	// have room for all of them
+	EnqueueAll([]*FunctionDescriptor) error
+
+	// DequeueBatch returns up to max functions, waiting at most the given duration
+	// for at least one.  A duration of -1 waits forever.  Returns ErrEmptyQueue if
+	// there were no functions in time, ErrIllegalCount if max is less than one and
+	// ErrIllegalDuration if the duration is less than -1
+	DequeueBatch(max int, d time.Duration) ([]*FunctionDescriptor, error)
+}
+
+// FairShareFunctionQueue is a FunctionQueue shared by several tenants.  Each
+// tenant has its own queue, and functions are dequeued from the tenants with
+// queued functions in weighted round-robin order, so a tenant that floods the
+// queue cannot starve the others.  Functions given to Enqueue belong to the
+// tenant named by DefaultTenant
+type FairShareFunctionQueue interface {
+	ContextFunctionQueue
+
+	// EnqueueForTenant queues a function to be run in the pool for the given tenant.
+	// Returns ErrAtCapacity if either the queue or the tenant is at capacity
+	EnqueueForTenant(tenant string, userCall interface{}, args ...interface{}) error
+
+	// SetTenant sets the weight and capacity of a tenant.  A tenant with a weight
+	// of two has two functions dequeued for every one dequeued for a tenant with
+	// a weight of one.  A capacity of zero means the tenant is only limited by the
+	// capacity of the queue.  Tenants that are not set have a weight of one and the
+	// tenant capacity given to NewFairShareFunctionQueue.  Returns ErrIllegalCount
+	// if the weight is zero
+	SetTenant(tenant string, weight uint32, capacity uint32) error
+
+	// GetTenantSize returns the number of functions queued for the given tenant
+	GetTenantSize(tenant string) int
+
+	// GetTenants returns the sorted names of the tenants that have been set
+	// with SetTenant or that have functions queued
+	GetTenants() []string
+}
+
+// CoalescingFunctionQueue is a FunctionQueue that holds at most one queued
+// function for each key.  Enqueuing a function with the key of a function
+// that is still queued coalesces the two according to the CoalescePolicy of
+// the queue.  Functions given to Enqueue have no key and are never coalesced
+type CoalescingFunctionQueue interface {
+	ContextFunctionQueue
+
+	// EnqueueKeyed queues a function with the given key to be run in the pool.  If
+	// a function with the same key is already queued the two are coalesced, which
+	// succeeds even if the queue is at capacity.  Otherwise returns ErrAtCapacity
+	// if the queue is currently at capacity
+	EnqueueKeyed(key string, userCall interface{}, args ...interface{}) error
+
+	// GetCoalescePolicy returns the policy used when two functions have the same key
+	GetCoalescePolicy() CoalescePolicy
+
+	// GetCoalescedCount returns the number of functions given to EnqueueKeyed
+	// that were coalesced with a function that was already queued
+	GetCoalescedCount() uint64
+}
+
+// PriorityFunctionQueue is a FunctionQueue that returns functions in priority
+// order, as determined by the comparator given to NewPriorityFunctionQueue.
+// Functions with equal priority are returned in the order they were enqueued.
+// Functions given to Enqueue have a nil priority, which is returned after all
+// other priorities
+type PriorityFunctionQueue interface {
+	ContextFunctionQueue
+
+	// EnqueueWithPriority queues a function with the given priority to be run
+	// in the pool.  Returns ErrAtCapacity if the queue is currently at capacity
+	EnqueueWithPriority(priority interface{}, userCall interface{}, args ...interface{}) error
+
+	// SetAgingDuration sets how long a function may wait on the queue before it is
+	// returned ahead of functions with a higher priority, which keeps low priority
+	// functions from starving.  Aged functions are returned in the order they were
+	// enqueued.  A duration of 0 turns aging off, which is the default.  Returns
+	// ErrIllegalDuration if the duration is negative
+	SetAgingDuration(time.Duration) error
+
+	// GetAgingDuration returns the aging duration of this queue
+	GetAgingDuration() time.Duration
+}
+
+// DelayFunctionQueue is a FunctionQueue where functions are not returned
+// before the time they are due.  Due functions are returned in the order
+// they became due.  GetSize and IsEmpty only consider functions that are due,
+// and the state change callback is called when a function becomes due, which
+// lets a Pool be used to run scheduled work.  Functions given to Enqueue are
+// due immediately
+type DelayFunctionQueue interface {
+	ContextFunctionQueue
+
+	// EnqueueAt queues a function that is due at the given time.  Returns
+	// ErrAtCapacity if the queue is currently at capacity
+	EnqueueAt(due time.Time, userCall interface{}, args ...interface{}) error
+
+	// EnqueueAfter queues a function that is due after the given duration.
+	// Returns ErrAtCapacity if the queue is currently at capacity and
+	// ErrIllegalDuration if the duration is negative
+	EnqueueAfter(delay time.Duration, userCall interface{}, args ...interface{}) error
+
+	// GetDelayedSize returns the number of functions on the queue that are not yet due
+	GetDelayedSize() int
+
+	// RemoveAll removes every function from the queue, whether or not it
+	// is due, and returns them in the order they are due
+	RemoveAll() []*FunctionDescriptor
+}
+
+// PersistentFunctionQueue is a FunctionQueue that writes the tasks given to
+// it to an append-only log in a local directory.  Tasks are calls to named
+// handlers with arguments that can be serialized as JSON.  A task is
+// acknowledged in the log once its handler returns without panicking, and
+// tasks that were never acknowledged can be replayed when the queue is next
+// created with the same directory.  Lines of the log that cannot be read
+// are skipped
+type PersistentFunctionQueue interface {
+	ContextFunctionQueue
+
+	// RegisterHandler registers a function under the given name.  The arguments
+	// of the function must be JSON serializable and the function may not be
+	// variadic.  Handlers must be registered before tasks for them are enqueued
+	// or replayed
+	RegisterHandler(name string, handler interface{}) error
+
+	// EnqueueTask queues a call to the named handler with the given arguments.
+	// The task is written to the log before this returns.  Returns
+	// ErrUnknownHandler if no handler is registered with the given name and
+	// ErrAtCapacity if the queue is currently at capacity.  Enqueue can also be
+	// used, in which case the userCall must be the name of the handler
+	EnqueueTask(name string, args ...interface{}) error
+
+	// Replay queues every task found in the log when this queue was created
+	// that was never acknowledged, in the order they were originally enqueued.
+	// Replayed tasks are not subject to the capacity of the queue.  Returns the
+	// number of tasks queued.  Returns ErrUnknownHandler if a task refers to a
+	// handler that is not registered, in which case that and any later tasks
+	// remain to be replayed
+	Replay() (int, error)
+
+	// SetErrorQueue sets the queue on which errors writing the acknowledgement
+	// of a task to the log are placed.  A task whose acknowledgement could not
+	// be written can be replayed even though its handler returned
+	SetErrorQueue(ErrorQueue)
+
+	// GetErrorQueue returns the error queue of this queue, which may be nil
+	GetErrorQueue() ErrorQueue
+
+	// GetDirectory returns the directory holding the log of this queue
+	GetDirectory() string
+
+	// Close closes the log of this queue.  Tasks that are still on the queue or
+	// running when the queue is closed are not acknowledged, and so can be
+	// replayed.  Enqueue returns ErrQueueClosed after the queue is closed
+	Close() error
+}
+
 // ErrorInformation represents data about an error that occurred
 type ErrorInformation interface {
 	// GetThreadID returns the thread id on which the error occurred
@@ -295,6 +1072,19 @@ type ErrorInformation interface {
 	GetError() error
 }
 
+// PanicError is the error reported when user code running on a goethe
+// thread panics.  It is the error of the ErrorInformation placed on
+// ErrorQueues and given to the panic handler
+type PanicError interface {
+	error
+
+	// GetPanicValue returns the value that was given to panic
+	GetPanicValue() interface{}
+
+	// GetStack returns the stack trace of the goethe thread at the time of the panic
+	GetStack() []byte
+}
+
 // ErrorQueue is used to retrieve errors thrown by the functions
 // given to the thread pool.  Any implementation of this interface
 // can be used by the system, or you can use the ones returned by
@@ -317,6 +1107,29 @@ type ErrorQueue interface {
 	IsEmpty() bool
 }
 
+// RingErrorQueue is an ErrorQueue that keeps the most recent errors.  When
+// the queue is full Enqueue overwrites the oldest error rather than
+// returning ErrAtCapacity, and counts the overwritten error as dropped
+type RingErrorQueue interface {
+	ErrorQueue
+
+	// DequeueAll removes every error from the queue and returns
+	// them, oldest first.  Returns an empty slice if there are no errors
+	DequeueAll() []ErrorInformation
+
+	// Peek returns the most recent error without removing it.  Dequeue
+	// returns the oldest error, so this is the error Dequeue returns last.
+	// If there were no errors on the queue the second return value is false
+	Peek() (ErrorInformation, bool)
+
+	// GetCapacity returns the number of errors this queue keeps
+	GetCapacity() uint32
+
+	// GetDroppedCount returns the number of errors that have been
+	// overwritten by newer errors since this queue was created
+	GetDroppedCount() uint64
+}
+
 var (
 	// ErrReadLockHeld returned if a WriteLock call is made while holding a ReadLock
 	ErrReadLockHeld = errors.New("attempted to acquire a WriteLock while ReadLock was held")
@@ -327,6 +1140,13 @@ var (
 	// ErrWriteLockNotHeld returned if a call to WriteUnlock is made while not holding the WriteLock
 	ErrWriteLockNotHeld = errors.New("write lock is not held by this thread")
 
+	// ErrReadLockNotHeld returned if a call to TryUpgrade is made while not holding the ReadLock
+	ErrReadLockNotHeld = errors.New("read lock is not held by this thread")
+
+	// ErrUpgradeInProgress returned by TryUpgrade if another thread is already waiting to upgrade
+	// the lock.  The calling thread should release its ReadLock
+	ErrUpgradeInProgress = errors.New("another thread is already upgrading this lock")
+
 	// ErrAtCapacity returned by FunctionQueue.Enqueue if the queue is currently at capacity
 	ErrAtCapacity = errors.New("queue is at capacity")
 
@@ -345,9 +1165,116 @@ var (
 
 	// ErrTryLockDurationIllegal One of the TryLock methods was called with an illegal duration
 	ErrTryLockDurationIllegal = errors.New("illegal duration (< -1) passed to TryLock")
+
+	// ErrIllegalDuration a method that waits for some amount of time was called with an illegal duration
+	ErrIllegalDuration = errors.New("illegal duration (< -1) given")
+
+	// ErrFutureNotDone returned by Future.Get if the function did not complete in the given duration
+	ErrFutureNotDone = errors.New("future did not complete in the given duration")
+
+	// ErrFutureCancelled returned by Future.Get if the future was cancelled before the function ran
+	ErrFutureCancelled = errors.New("future was cancelled")
+
+	// ErrIllegalPermits returned when a negative number of semaphore permits is given
+	ErrIllegalPermits = errors.New("illegal number of permits (< 0) given")
+
+	// ErrPermitsLeaked placed on the ErrorQueue of a tracked semaphore when a goethe thread
+	// ends while holding permits
+	ErrPermitsLeaked = errors.New("goethe thread ended while holding semaphore permits")
+
+	// ErrUnknownHandler returned by PersistentFunctionQueue if no handler is registered with a name
+	ErrUnknownHandler = errors.New("no handler registered with the given name")
+
+	// ErrQueueClosed returned if a queue has been closed
+	ErrQueueClosed = errors.New("queue has been closed")
+
+	// ErrIllegalCount returned when an illegal count or number of parties is given
+	ErrIllegalCount = errors.New("illegal count given")
+
+	// ErrTimeout returned by a barrier or phaser if the given duration passed while waiting
+	ErrTimeout = errors.New("timed out while waiting")
+
+	// ErrBarrierBroken returned by CyclicBarrier.Await if the barrier is broken
+	ErrBarrierBroken = errors.New("barrier is broken")
+
+	// ErrPhaserTerminated returned by Phaser if the phaser has terminated
+	ErrPhaserTerminated = errors.New("phaser is terminated")
+
+	// ErrPhaserNoParties returned by Phaser if every registered party has already arrived
+	ErrPhaserNoParties = errors.New("all registered parties of the phaser have arrived")
+
+	// ErrDeadlock is matched by the DeadlockError returned by a lock call that would deadlock
+	// when deadlock detection is enabled
+	ErrDeadlock = errors.New("deadlock detected")
+)
+
+// PanicPolicy determines what happens when user code running on a goethe thread panics
+type PanicPolicy int
+
+const (
+	// PanicRecover recovers from the panic and reports it as a PanicError
+	PanicRecover PanicPolicy = iota
+
+	// PanicRecoverAndLog is the same as PanicRecover but also logs the panic along
+	// with its stack trace
+	PanicRecoverAndLog
+
+	// PanicRepanic reports the panic as a PanicError and then panics again, which
+	// will end the process
+	PanicRepanic
+)
+
+// RejectionPolicy determines what a Pool does with a function given to Submit
+// or SubmitContext when its FunctionQueue is at capacity
+type RejectionPolicy int
+
+const (
+	// RejectAbort returns ErrAtCapacity to the caller, this is the default
+	RejectAbort RejectionPolicy = iota
+
+	// RejectCallerRuns runs the function on the calling thread before returning
+	RejectCallerRuns
+
+	// RejectDiscardOldest removes the next function from the FunctionQueue,
+	// cancelling it if it was given to Submit, and queues the new function
+	RejectDiscardOldest
+
+	// RejectDiscardNewest drops the new function, whose Future is returned
+	// already cancelled
+	RejectDiscardNewest
+
+	// RejectBlock waits for room on the FunctionQueue, returning ErrAtCapacity
+	// if there is still no room once the block timeout has passed
+	RejectBlock
+)
+
+// CoalescePolicy determines which function a CoalescingFunctionQueue keeps
+// when a function is enqueued with the key of a function that is still queued
+type CoalescePolicy int
+
+const (
+	// CoalesceKeepFirst keeps the function that is already queued and drops the new one
+	CoalesceKeepFirst CoalescePolicy = iota
+
+	// CoalesceKeepLast replaces the function and arguments that are already queued
+	// with the new ones.  The function keeps its place in the queue
+	CoalesceKeepLast
+)
+
+const (
+	// DefaultTenant is the tenant of functions given to FairShareFunctionQueue.Enqueue
+	DefaultTenant = ""
 )
 
 const (
 	// TimerThreadLocal A thread local with this name will have the Timer when called from a scheuled job
 	TimerThreadLocal = "goethe.Timer"
+
+	// ContextThreadLocal A thread local with this name will have the context given to GoContext
+	// or Pool.SubmitContext.  The context should normally be retrieved with GetContext
+	ContextThreadLocal = "goethe.Context"
+
+	// SemaphoreThreadLocal A thread local with this name is used to keep track of the tracked
+	// semaphores a goethe thread has used
+	SemaphoreThreadLocal = "goethe.Semaphores"
 )
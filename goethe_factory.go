@@ -41,8 +41,10 @@
 package goethe
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"reflect"
 	"runtime/debug"
 	"strings"
@@ -60,6 +62,12 @@ type timersData struct {
 	timer    timerImpl
 }
 
+type panicData struct {
+	panicMux sync.Mutex
+	policy   PanicPolicy
+	handler  func(ErrorInformation)
+}
+
 type threadLocalsData struct {
 	localsMux    sync.Mutex
 	threadLocals map[string]*threadLocalOperators
@@ -76,6 +84,10 @@ type StandardThreadUtilities struct {
 	pools  *poolData
 	timers *timersData
 	locals *threadLocalsData
+	panics *panicData
+
+	lastLockID uint64
+	deadlocks  *deadlockDetector
 }
 
 type threadLocalOperators struct {
@@ -105,13 +117,22 @@ func newGoethe() *StandardThreadUtilities {
 		threadLocals: make(map[string]*threadLocalOperators),
 	}
 
+	panics := &panicData{
+		policy: PanicRecoverAndLog,
+	}
+
 	retVal := &StandardThreadUtilities{
-		lastTid: 9,
-		pools:   pools,
-		timers:  timers,
-		locals:  locals,
+		lastTid:   9,
+		pools:     pools,
+		timers:    timers,
+		locals:    locals,
+		panics:    panics,
+		deadlocks: newDeadlockDetector(),
 	}
 
+	retVal.EstablishThreadLocal(SemaphoreThreadLocal, nil, retVal.reportSemaphoreLeaks)
+	retVal.EstablishThreadLocal(ContextThreadLocal, nil, nil)
+
 	return retVal
 }
 
@@ -157,6 +178,128 @@ func (goth *StandardThreadUtilities) Go(userCall interface{}, args ...interface{
 	return tid, nil
 }
 
+// GoWithFuture is the same as Go except that it returns a Future
+// which can be used to wait for the function to complete and to get
+// all of the values returned by the function.  The thread id can
+// be found on the returned Future
+func (goth *StandardThreadUtilities) GoWithFuture(userCall interface{}, args ...interface{}) (Future, error) {
+	tid := goth.getAndIncrementTid()
+
+	argArray := make([]interface{}, len(args))
+	for index, arg := range args {
+		argArray[index] = arg
+	}
+
+	arguments, err := getValues(userCall, argArray)
+	if err != nil {
+		return nil, err
+	}
+
+	// The thread id of the future is set once the thread runs the function
+	future := newFuture(userCall, arguments, nil, goth.GetPanicPolicy())
+
+	go invokeStart(tid, future.run, []reflect.Value{})
+
+	return future, nil
+}
+
+// GoContext is the same as Go except that the given context can be retrieved
+// with GetContext from inside the goethe thread running the function
+func (goth *StandardThreadUtilities) GoContext(ctx context.Context, userCall interface{}, args ...interface{}) (int64, error) {
+	tid := goth.getAndIncrementTid()
+
+	argArray := make([]interface{}, len(args))
+	for index, arg := range args {
+		argArray[index] = arg
+	}
+
+	arguments, err := getValues(userCall, argArray)
+	if err != nil {
+		return -1, err
+	}
+
+	go invokeStart(tid, func() {
+		goth.setThreadContext(ctx)
+
+		invoke(userCall, arguments, nil, goth.GetPanicPolicy())
+	}, []reflect.Value{})
+
+	return tid, nil
+}
+
+// GetContext returns the context associated with the current goethe thread
+// by GoContext or Pool.SubmitContext.  If there is no such context then
+// context.Background() is returned.  Will return ErrNotGoetheThread if
+// called from a non-goethe thread
+func (goth *StandardThreadUtilities) GetContext() (context.Context, error) {
+	tl, err := goth.GetThreadLocal(ContextThreadLocal)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := tl.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, ok := raw.(context.Context)
+	if !ok || ctx == nil {
+		return context.Background(), nil
+	}
+
+	return ctx, nil
+}
+
+func (goth *StandardThreadUtilities) setThreadContext(ctx context.Context) error {
+	_, err := goth.exchangeThreadContext(ctx)
+	return err
+}
+
+// swapThreadContext sets the context of the calling goethe thread and
+// returns the context it had before, which may be nil
+func (goth *StandardThreadUtilities) swapThreadContext(ctx context.Context) context.Context {
+	previous, _ := goth.exchangeThreadContext(ctx)
+	return previous
+}
+
+// exchangeThreadContext replaces the context thread local of the calling
+// goethe thread.  The locals lock is held for the whole read-modify so
+// that it cannot interleave with removeAllActuals
+func (goth *StandardThreadUtilities) exchangeThreadContext(ctx context.Context) (context.Context, error) {
+	tid := goth.GetThreadID()
+	if tid < int64(0) {
+		return nil, ErrNotGoetheThread
+	}
+
+	goth.locals.localsMux.Lock()
+	defer goth.locals.localsMux.Unlock()
+
+	operators := goth.locals.threadLocals[ContextThreadLocal]
+
+	operators.lock.WriteLock()
+	defer operators.lock.WriteUnlock()
+
+	actual, found := operators.actuals[tid]
+	if !found {
+		actual = newThreadLocal(ContextThreadLocal, goth, tid)
+		operators.actuals[tid] = actual
+	}
+
+	raw, err := actual.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	err = actual.Set(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, _ := raw.(context.Context)
+
+	return previous, nil
+}
+
 // GetThreadID Gets the current threadID.  Returns -1
 // if this is not a goethe thread.  Thread ids start at 10
 // as thread ids 0 through 9 are reserved for future use
@@ -191,7 +334,54 @@ func (goth *StandardThreadUtilities) GetThreadID() int64 {
 
 // NewGoetheLock Creates a new goethe lock
 func (goth *StandardThreadUtilities) NewGoetheLock() Lock {
-	return newReaderWriterLock(goth)
+	return newReaderWriterLock(goth, "", false)
+}
+
+// NewNamedGoetheLock creates a new goethe lock with the given name
+func (goth *StandardThreadUtilities) NewNamedGoetheLock(name string) Lock {
+	return newReaderWriterLock(goth, name, false)
+}
+
+// NewFairGoetheLock creates a new goethe lock that is granted in arrival order
+func (goth *StandardThreadUtilities) NewFairGoetheLock(name string) Lock {
+	return newReaderWriterLock(goth, name, true)
+}
+
+// NewSemaphore creates a counting semaphore with the given number of permits
+func (goth *StandardThreadUtilities) NewSemaphore(permits int32) (Semaphore, error) {
+	return newSemaphore(goth, permits, false, nil)
+}
+
+// NewTrackedSemaphore creates a counting semaphore with the given number of permits
+// that reports permits still held by goethe threads when they end to the ErrorQueue
+func (goth *StandardThreadUtilities) NewTrackedSemaphore(permits int32, leaks ErrorQueue) (Semaphore, error) {
+	return newSemaphore(goth, permits, true, leaks)
+}
+
+// NewCountDownLatch creates a latch that opens after count calls to CountDown
+func (goth *StandardThreadUtilities) NewCountDownLatch(count int32) (CountDownLatch, error) {
+	return newCountDownLatch(goth, count)
+}
+
+// NewCyclicBarrier creates a barrier for the given number of parties
+func (goth *StandardThreadUtilities) NewCyclicBarrier(parties int32, action func([]int64)) (CyclicBarrier, error) {
+	return newCyclicBarrier(goth, parties, action)
+}
+
+// NewPhaser creates a phaser with the given number of registered parties
+func (goth *StandardThreadUtilities) NewPhaser(parties int32, onAdvance func(int32, []int64)) (Phaser, error) {
+	return newPhaser(goth, parties, onAdvance)
+}
+
+// EnableDeadlockDetection starts tracking goethe locks for deadlocks.  See
+// the ThreadUtilities interface for details
+func (goth *StandardThreadUtilities) EnableDeadlockDetection(failWaiter bool, handler func(*DeadlockError)) {
+	goth.deadlocks.enable(failWaiter, handler)
+}
+
+// DisableDeadlockDetection stops tracking goethe locks for deadlocks
+func (goth *StandardThreadUtilities) DisableDeadlockDetection() {
+	goth.deadlocks.disable()
 }
 
 // NewPool creates a new thread pool with the given parameters.  The name is the
@@ -242,6 +432,11 @@ func (goth *StandardThreadUtilities) GetPool(name string) (Pool, bool) {
 	return retVal, found
 }
 
+// NewForkJoinPool creates and starts a fork/join pool with the given number of threads
+func (goth *StandardThreadUtilities) NewForkJoinPool(parallelism int32, errorQueue ErrorQueue) (ForkJoinPool, error) {
+	return newForkJoinPool(goth, parallelism, errorQueue)
+}
+
 // EstablishThreadLocal tells the system of the named thread local storage
 // initialize method and destroy method.  This method can be called on any
 // thread, including non-goethe threads.  Both the initializer and
@@ -250,7 +445,7 @@ func (goth *StandardThreadUtilities) GetPool(name string) (Pool, bool) {
 func (goth *StandardThreadUtilities) EstablishThreadLocal(name string, initializer func(ThreadLocal) error,
 	destroyer func(ThreadLocal) error) error {
 	goth.locals.localsMux.Lock()
-	goth.locals.localsMux.Unlock()
+	defer goth.locals.localsMux.Unlock()
 
 	_, found := goth.locals.threadLocals[name]
 	if found {
@@ -281,17 +476,7 @@ func (goth *StandardThreadUtilities) GetThreadLocal(name string) (ThreadLocal, e
 		return nil, ErrNotGoetheThread
 	}
 
-	operators, found := goth.getOperatorsByName(name)
-	if !found {
-		operators = &threadLocalOperators{
-			lock:    goth.NewGoetheLock(),
-			actuals: make(map[int64]ThreadLocal),
-		}
-
-		goth.locals.localsMux.Lock()
-		goth.locals.threadLocals[name] = operators
-		goth.locals.localsMux.Unlock()
-	}
+	operators := goth.getOrCreateOperators(name)
 
 	operators.lock.WriteLock()
 	defer operators.lock.WriteUnlock()
@@ -384,13 +569,94 @@ func (goth *StandardThreadUtilities) ScheduleWithFixedDelay(initialDelay time.Du
 	return goth.timers.timer.addJob(initialDelay, delay, errorQueue, method, arguments, false)
 }
 
-func (goth *StandardThreadUtilities) getOperatorsByName(name string) (*threadLocalOperators, bool) {
+// SetPanicPolicy sets the policy used when user code running on a goethe thread
+// started by this ThreadUtilities (including timer threads) panics.  The default
+// policy is PanicRecoverAndLog.  Pools have their own policy, which is initialized
+// from this policy when the pool is created
+func (goth *StandardThreadUtilities) SetPanicPolicy(policy PanicPolicy) {
+	goth.panics.panicMux.Lock()
+	defer goth.panics.panicMux.Unlock()
+
+	goth.panics.policy = policy
+}
+
+// GetPanicPolicy returns the policy used when user code running on a goethe
+// thread panics
+func (goth *StandardThreadUtilities) GetPanicPolicy() PanicPolicy {
+	goth.panics.panicMux.Lock()
+	defer goth.panics.panicMux.Unlock()
+
+	return goth.panics.policy
+}
+
+// SetPanicHandler sets a function that is called with every panic recovered on
+// any goethe thread.  The error of the ErrorInformation given to the handler
+// will be a PanicError.  The handler may be nil
+func (goth *StandardThreadUtilities) SetPanicHandler(handler func(ErrorInformation)) {
+	goth.panics.panicMux.Lock()
+	defer goth.panics.panicMux.Unlock()
+
+	goth.panics.handler = handler
+}
+
+func (goth *StandardThreadUtilities) getPanicHandler() func(ErrorInformation) {
+	goth.panics.panicMux.Lock()
+	defer goth.panics.panicMux.Unlock()
+
+	return goth.panics.handler
+}
+
+// handlePanic reports the recovered value to the error queue (which may be nil)
+// and the panic handler and then follows the policy.  It must be called from the
+// deferred function that recovered the panic so that the stack trace includes the
+// code that panicked
+func (goth *StandardThreadUtilities) handlePanic(recovered interface{}, errorQueue ErrorQueue,
+	policy PanicPolicy) error {
+	if reported, ok := recovered.(*panicError); ok {
+		// Already reported by an inner invocation whose policy was to panic again
+		panic(reported)
+	}
+
+	tid := goth.GetThreadID()
+	panicErr := newPanicError(recovered, debug.Stack())
+	errInfo := newErrorinformation(tid, panicErr)
+
+	if errorQueue != nil {
+		errorQueue.Enqueue(errInfo)
+	}
+
+	handler := goth.getPanicHandler()
+	if handler != nil {
+		handler(errInfo)
+	}
+
+	switch policy {
+	case PanicRecoverAndLog:
+		log.Printf("goethe thread %d panicked: %v\n%s", tid, recovered, panicErr.stack)
+	case PanicRepanic:
+		panic(panicErr)
+	}
+
+	return panicErr
+}
+
+// getOrCreateOperators returns the operators of the named thread local,
+// adding ones with no initializer/destroyer if it was never established
+func (goth *StandardThreadUtilities) getOrCreateOperators(name string) *threadLocalOperators {
 	goth.locals.localsMux.Lock()
-	goth.locals.localsMux.Unlock()
+	defer goth.locals.localsMux.Unlock()
+
+	operators, found := goth.locals.threadLocals[name]
+	if !found {
+		operators = &threadLocalOperators{
+			lock:    goth.NewGoetheLock(),
+			actuals: make(map[int64]ThreadLocal),
+		}
 
-	retVal, found := goth.locals.threadLocals[name]
+		goth.locals.threadLocals[name] = operators
+	}
 
-	return retVal, found
+	return operators
 }
 
 func removeThreadLocal(operators *threadLocalOperators, tid int64) {
@@ -411,9 +677,13 @@ func removeThreadLocal(operators *threadLocalOperators, tid int64) {
 
 func (goth *StandardThreadUtilities) removeAllActuals(tid int64) {
 	goth.locals.localsMux.Lock()
+	allOperators := make([]*threadLocalOperators, 0, len(goth.locals.threadLocals))
+	for _, operators := range goth.locals.threadLocals {
+		allOperators = append(allOperators, operators)
+	}
 	goth.locals.localsMux.Unlock()
 
-	for _, operators := range goth.locals.threadLocals {
+	for _, operators := range allOperators {
 		removeThreadLocal(operators, tid)
 	}
 }
@@ -444,7 +714,7 @@ func invokeStart(tid int64, userCall interface{}, args []reflect.Value) error {
 func invokeEnd(tid int64, userCall interface{}, args []reflect.Value) error {
 	defer globalGoethe.removeAllActuals(tid)
 
-	invoke(userCall, args, nil)
+	invoke(userCall, args, nil, globalGoethe.GetPanicPolicy())
 
 	return nil
 }
@@ -41,13 +41,18 @@
 package goethe
 
 import (
+	"context"
 	"io"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type goetheLock struct {
 	parent *StandardThreadUtilities
+	id     uint64
+	name   string
 
 	goMux   sync.Mutex
 	cond    *sync.Cond
@@ -57,16 +62,36 @@ type goetheLock struct {
 
 	holdingWriter  int64
 	writerCount    int32
-	writersWaiting int64
+	upgrader       int64
+	waitingWriters map[int64]bool
+	waitingReaders map[int64]bool
 	jobNumber      uint64
+
+	// fairQueue is only used by fair locks and is in arrival order
+	fair      bool
+	fairQueue []*fairWaiter
+
+	waitStatistics map[int64]*LockWaitStatistics
+}
+
+type fairWaiter struct {
+	tid   int64
+	write bool
 }
 
-func newReaderWriterLock(pparent *StandardThreadUtilities) Lock {
+func newReaderWriterLock(pparent *StandardThreadUtilities, name string, fair bool) Lock {
 	retVal := &goetheLock{
-		parent:        pparent,
-		holdingWriter: -2,
-		readerCounts:  make(map[int64]int32),
-		sleeper:       newSleeper(),
+		parent:         pparent,
+		id:             atomic.AddUint64(&pparent.lastLockID, 1),
+		name:           name,
+		fair:           fair,
+		waitStatistics: make(map[int64]*LockWaitStatistics),
+		holdingWriter:  -2,
+		upgrader:       -2,
+		readerCounts:   make(map[int64]int32),
+		waitingWriters: make(map[int64]bool),
+		waitingReaders: make(map[int64]bool),
+		sleeper:        newSleeper(),
 	}
 
 	retVal.cond = sync.NewCond(&retVal.goMux)
@@ -97,7 +122,18 @@ func (lock *goetheLock) ReadLock() error {
 	return err
 }
 
+// ReadLockContext is the same as ReadLock but will stop waiting for the lock
+// if the context is done, in which case the error of the context is returned
+func (lock *goetheLock) ReadLockContext(ctx context.Context) error {
+	_, err := lock.tryReadLock(ctx, -1)
+	return err
+}
+
 func (lock *goetheLock) TryReadLock(d time.Duration) (bool, error) {
+	return lock.tryReadLock(context.Background(), d)
+}
+
+func (lock *goetheLock) tryReadLock(ctx context.Context, d time.Duration) (bool, error) {
 	if d < -1 {
 		return false, ErrTryLockDurationIllegal
 	}
@@ -129,7 +165,34 @@ func (lock *goetheLock) TryReadLock(d time.Duration) (bool, error) {
 		}
 	}()
 
-	for lock.holdingWriter >= 0 || lock.writersWaiting > 0 {
+	var ctxCloser io.Closer
+	defer func() {
+		if ctxCloser != nil {
+			ctxCloser.Close()
+		}
+	}()
+
+	if lock.fair && lock.getMyReadCount(tid) == 0 {
+		// Readers that already hold the lock do not wait their turn
+		lock.enterFairQueue(tid, false)
+		defer lock.leaveFairQueue(tid)
+	}
+
+	var waitStart time.Time
+	waitRecorded := false
+	defer func() {
+		if waitRecorded {
+			delete(lock.waitingReaders, tid)
+			lock.parent.deadlocks.stopWaiting(tid)
+			lock.recordWait(tid, time.Since(waitStart))
+		}
+	}()
+
+	for lock.isReadBlocked(tid) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
 		if d >= 0 && (now.Equal(endTime) || now.After(endTime)) {
 			return false, nil
 		}
@@ -146,6 +209,20 @@ func (lock *goetheLock) TryReadLock(d time.Duration) (bool, error) {
 			closeMe = lock.sleeper.sleep(remainingDuration, lock.cond, lock.jobNumber)
 		}
 
+		if ctxCloser == nil && ctx.Done() != nil {
+			ctxCloser = broadcastOnDone(ctx, lock.cond)
+		}
+
+		if !waitRecorded {
+			waitRecorded = true
+			waitStart = time.Now()
+			lock.waitingReaders[tid] = true
+
+			if deadlock := lock.parent.deadlocks.startWaiting(lock, tid, false); deadlock != nil {
+				return false, deadlock
+			}
+		}
+
 		lock.cond.Wait()
 
 		now = time.Now()
@@ -155,12 +232,110 @@ func (lock *goetheLock) TryReadLock(d time.Duration) (bool, error) {
 		closeMe.Close()
 	}
 
-	// At this point holdingWriter < 0 and there are no writersWaiting
+	// At this point holdingWriter < 0 and it is the turn of this reader
 	lock.incrementReadLock(tid)
 
 	return true, nil
 }
 
+// isReadBlocked must have mutex held.  Readers of an unfair lock wait for
+// any waiting writer.  Readers of a fair lock wait until every thread that
+// arrived before them is a reader
+func (lock *goetheLock) isReadBlocked(tid int64) bool {
+	if lock.holdingWriter >= 0 {
+		return true
+	}
+
+	if !lock.fair {
+		return len(lock.waitingWriters) > 0
+	}
+
+	for _, waiter := range lock.fairQueue {
+		if waiter.tid == tid {
+			return false
+		}
+		if waiter.write {
+			return true
+		}
+	}
+
+	// Not in the queue, already holds the lock for read
+	return false
+}
+
+// isWriteBlocked must have mutex held.  Writers of a fair lock also wait
+// until they are the first thread in the queue
+func (lock *goetheLock) isWriteBlocked(tid int64, upgrade bool) bool {
+	if lock.holdingWriter >= 0 || lock.getAllOtherReadCount(tid) > 0 {
+		return true
+	}
+
+	if !lock.fair || upgrade {
+		return false
+	}
+
+	return len(lock.fairQueue) > 0 && lock.fairQueue[0].tid != tid
+}
+
+// enterFairQueue must have mutex held
+func (lock *goetheLock) enterFairQueue(tid int64, write bool) {
+	lock.fairQueue = append(lock.fairQueue, &fairWaiter{
+		tid:   tid,
+		write: write,
+	})
+}
+
+// leaveFairQueue must have mutex held.  Whether or not the thread got the
+// lock the threads behind it may now be able to go
+func (lock *goetheLock) leaveFairQueue(tid int64) {
+	for index, waiter := range lock.fairQueue {
+		if waiter.tid == tid {
+			lock.fairQueue = append(lock.fairQueue[:index], lock.fairQueue[index+1:]...)
+			break
+		}
+	}
+
+	lock.cond.Broadcast()
+}
+
+// recordWait must have mutex held
+func (lock *goetheLock) recordWait(tid int64, waited time.Duration) {
+	stats, found := lock.waitStatistics[tid]
+	if !found {
+		stats = &LockWaitStatistics{}
+		lock.waitStatistics[tid] = stats
+	}
+
+	stats.Waits++
+	stats.TotalWait += waited
+	if waited > stats.MaxWait {
+		stats.MaxWait = waited
+	}
+}
+
+func (lock *goetheLock) IsFair() bool {
+	return lock.fair
+}
+
+func (lock *goetheLock) GetWaitStatistics() map[int64]LockWaitStatistics {
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	retVal := make(map[int64]LockWaitStatistics, len(lock.waitStatistics))
+	for tid, stats := range lock.waitStatistics {
+		retVal[tid] = *stats
+	}
+
+	return retVal
+}
+
+func (lock *goetheLock) ResetWaitStatistics() {
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	lock.waitStatistics = make(map[int64]*LockWaitStatistics)
+}
+
 func (lock *goetheLock) incrementReadLock(tid int64) {
 	currentValue, found := lock.readerCounts[tid]
 	if found {
@@ -168,6 +343,7 @@ func (lock *goetheLock) incrementReadLock(tid int64) {
 		lock.readerCounts[tid] = currentValue
 	} else {
 		lock.readerCounts[tid] = 1
+		lock.parent.deadlocks.acquired(lock, tid, false)
 	}
 }
 
@@ -191,8 +367,9 @@ func (lock *goetheLock) ReadUnlock() error {
 	count--
 	if count <= 0 {
 		delete(lock.readerCounts, tid)
+		lock.parent.deadlocks.released(lock, tid, false)
 
-		if lock.writersWaiting > 0 {
+		if len(lock.waitingWriters) > 0 {
 			lock.cond.Broadcast()
 		}
 	} else {
@@ -234,7 +411,27 @@ func (lock *goetheLock) WriteLock() error {
 	return err
 }
 
+// WriteLockContext is the same as WriteLock but will stop waiting for the lock
+// if the context is done, in which case the error of the context is returned
+func (lock *goetheLock) WriteLockContext(ctx context.Context) error {
+	_, err := lock.tryWriteLock(ctx, -1)
+	return err
+}
+
 func (lock *goetheLock) TryWriteLock(d time.Duration) (bool, error) {
+	return lock.tryWriteLock(context.Background(), d)
+}
+
+// TryUpgrade acquires the write lock while the calling thread holds the read lock
+func (lock *goetheLock) TryUpgrade(d time.Duration) (bool, error) {
+	return lock.tryWriteLockInternal(context.Background(), d, true)
+}
+
+func (lock *goetheLock) tryWriteLock(ctx context.Context, d time.Duration) (bool, error) {
+	return lock.tryWriteLockInternal(ctx, d, false)
+}
+
+func (lock *goetheLock) tryWriteLockInternal(ctx context.Context, d time.Duration, upgrade bool) (bool, error) {
 	if d < -1 {
 		return false, ErrTryLockDurationIllegal
 	}
@@ -253,7 +450,11 @@ func (lock *goetheLock) TryWriteLock(d time.Duration) (bool, error) {
 	lock.goMux.Lock()
 	defer lock.goMux.Unlock()
 
-	if lock.getMyReadCount(tid) != 0 {
+	if upgrade {
+		if lock.holdingWriter != tid && lock.getMyReadCount(tid) == 0 {
+			return false, ErrReadLockNotHeld
+		}
+	} else if lock.getMyReadCount(tid) != 0 {
 		return false, ErrReadLockHeld
 	}
 
@@ -263,6 +464,18 @@ func (lock *goetheLock) TryWriteLock(d time.Duration) (bool, error) {
 		return true, nil
 	}
 
+	if upgrade {
+		if lock.upgrader >= 0 {
+			// Both would wait forever for the other to give up the read lock
+			return false, ErrUpgradeInProgress
+		}
+
+		lock.upgrader = tid
+		defer func() {
+			lock.upgrader = -2
+		}()
+	}
+
 	var closeMe io.Closer
 	defer func() {
 		if closeMe != nil {
@@ -270,10 +483,39 @@ func (lock *goetheLock) TryWriteLock(d time.Duration) (bool, error) {
 		}
 	}()
 
-	lock.writersWaiting++
-	for lock.holdingWriter >= 0 || lock.getAllOtherReadCount(tid) > 0 {
+	var ctxCloser io.Closer
+	defer func() {
+		if ctxCloser != nil {
+			ctxCloser.Close()
+		}
+	}()
+
+	if lock.fair && !upgrade {
+		// An upgrader already holds the lock so does not wait its turn
+		lock.enterFairQueue(tid, true)
+		defer lock.leaveFairQueue(tid)
+	}
+
+	var waitStart time.Time
+	waitRecorded := false
+	defer func() {
+		if waitRecorded {
+			lock.parent.deadlocks.stopWaiting(tid)
+			lock.recordWait(tid, time.Since(waitStart))
+		}
+	}()
+
+	lock.waitingWriters[tid] = true
+	for lock.isWriteBlocked(tid, upgrade) {
+		if err := ctx.Err(); err != nil {
+			delete(lock.waitingWriters, tid)
+			lock.cond.Broadcast()
+			return false, err
+		}
+
 		if d >= 0 && (now.Equal(endTime) || now.After(endTime)) {
-			lock.writersWaiting--
+			delete(lock.waitingWriters, tid)
+			lock.cond.Broadcast()
 			return false, nil
 		}
 
@@ -289,6 +531,21 @@ func (lock *goetheLock) TryWriteLock(d time.Duration) (bool, error) {
 			closeMe = lock.sleeper.sleep(remainingDuration, lock.cond, lock.jobNumber)
 		}
 
+		if ctxCloser == nil && ctx.Done() != nil {
+			ctxCloser = broadcastOnDone(ctx, lock.cond)
+		}
+
+		if !waitRecorded {
+			waitRecorded = true
+			waitStart = time.Now()
+
+			if deadlock := lock.parent.deadlocks.startWaiting(lock, tid, true); deadlock != nil {
+				delete(lock.waitingWriters, tid)
+				lock.cond.Broadcast()
+				return false, deadlock
+			}
+		}
+
 		lock.cond.Wait()
 
 		now = time.Now()
@@ -296,9 +553,10 @@ func (lock *goetheLock) TryWriteLock(d time.Duration) (bool, error) {
 
 	// I just got this lock for myself
 	lock.holdingWriter = tid
+	lock.parent.deadlocks.acquired(lock, tid, true)
 
 	lock.writerCount = 1
-	lock.writersWaiting--
+	delete(lock.waitingWriters, tid)
 	return true, nil
 }
 
@@ -321,6 +579,7 @@ func (lock *goetheLock) WriteUnlock() error {
 	if lock.writerCount <= 0 {
 		lock.writerCount = 0
 		lock.holdingWriter = -2
+		lock.parent.deadlocks.released(lock, tid, true)
 
 		lock.cond.Broadcast()
 	}
@@ -328,6 +587,109 @@ func (lock *goetheLock) WriteUnlock() error {
 	return nil
 }
 
+func (lock *goetheLock) GetName() string {
+	return lock.name
+}
+
+func (lock *goetheLock) GetWriteOwner() int64 {
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	if lock.holdingWriter < 0 {
+		return -1
+	}
+
+	return lock.holdingWriter
+}
+
+func (lock *goetheLock) GetReadHolders() map[int64]int32 {
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	retVal := make(map[int64]int32, len(lock.readerCounts))
+	for tid, count := range lock.readerCounts {
+		retVal[tid] = count
+	}
+
+	return retVal
+}
+
+func (lock *goetheLock) GetWaitingWriters() []int64 {
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	return sortedTids(lock.waitingWriters)
+}
+
+func (lock *goetheLock) GetWaitingReaders() []int64 {
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	return sortedTids(lock.waitingReaders)
+}
+
+func (lock *goetheLock) GetWriteHoldCount() int32 {
+	tid := lock.parent.GetThreadID()
+
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	if tid < 0 || lock.holdingWriter != tid {
+		return 0
+	}
+
+	return lock.writerCount
+}
+
+func (lock *goetheLock) GetReadHoldCount() int32 {
+	tid := lock.parent.GetThreadID()
+
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	return lock.getMyReadCount(tid)
+}
+
+func sortedTids(tids map[int64]bool) []int64 {
+	retVal := make([]int64, 0, len(tids))
+	for tid := range tids {
+		retVal = append(retVal, tid)
+	}
+
+	sort.Slice(retVal, func(i, j int) bool {
+		return retVal[i] < retVal[j]
+	})
+
+	return retVal
+}
+
+// DowngradeToRead turns the write holds of the calling thread into read holds
+// without letting another writer in
+func (lock *goetheLock) DowngradeToRead() error {
+	tid := lock.parent.GetThreadID()
+	if tid < 0 {
+		return ErrNotGoetheThread
+	}
+
+	lock.goMux.Lock()
+	defer lock.goMux.Unlock()
+
+	if tid != lock.holdingWriter {
+		return ErrWriteLockNotHeld
+	}
+
+	for ; lock.writerCount > 0; lock.writerCount-- {
+		lock.incrementReadLock(tid)
+	}
+
+	lock.holdingWriter = -2
+	lock.parent.deadlocks.released(lock, tid, true)
+
+	lock.cond.Broadcast()
+
+	return nil
+}
+
 func (lock *goetheLock) IsLocked() bool {
 	return lock.IsReadLocked() || lock.IsWriteLocked()
 }
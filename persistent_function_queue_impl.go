@@ -0,0 +1,565 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	persistentLogName = "goethe-queue.log"
+
+	persistentEnqueue = "enqueue"
+	persistentAck     = "ack"
+)
+
+type persistentFunctionQueue struct {
+	mux     sync.Mutex
+	cond    *sync.Cond
+	changer func(queue FunctionQueue)
+
+	capacity   uint32
+	directory  string
+	file       *os.File
+	offset     int64
+	damaged    bool
+	closed     bool
+	errorQueue ErrorQueue
+
+	handlers   map[string]interface{}
+	nextID     uint64
+	queue      []*FunctionDescriptor
+	unfinished []*persistentRecord
+}
+
+// persistentRecord is one line of the log
+type persistentRecord struct {
+	Op      string            `json:"op"`
+	ID      uint64            `json:"id"`
+	Handler string            `json:"handler,omitempty"`
+	Args    []json.RawMessage `json:"args,omitempty"`
+}
+
+// NewPersistentFunctionQueue creates a function queue with the given capacity
+// whose log is kept in the given directory, which is created if it does not
+// exist.  Tasks in the log that were never acknowledged are kept so that they
+// can be given to Replay once their handlers have been registered.  The log is
+// rewritten with only those tasks when the queue is created
+func NewPersistentFunctionQueue(directory string, userCapacity uint32) (PersistentFunctionQueue, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+
+	retVal := &persistentFunctionQueue{
+		capacity:  userCapacity,
+		directory: directory,
+		handlers:  make(map[string]interface{}),
+		queue:     make([]*FunctionDescriptor, 0),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	if err := retVal.openLog(); err != nil {
+		return nil, err
+	}
+
+	return retVal, nil
+}
+
+// openLog reads the existing log, compacts it down to the unfinished
+// tasks and opens it for appending
+func (pq *persistentFunctionQueue) openLog() error {
+	logPath := filepath.Join(pq.directory, persistentLogName)
+
+	records, err := readPersistentLog(logPath)
+	if err != nil {
+		return err
+	}
+
+	acked := make(map[uint64]bool)
+	for _, record := range records {
+		if record.ID >= pq.nextID {
+			pq.nextID = record.ID + 1
+		}
+
+		if record.Op == persistentAck {
+			acked[record.ID] = true
+		}
+	}
+
+	for _, record := range records {
+		if record.Op == persistentEnqueue && !acked[record.ID] {
+			pq.unfinished = append(pq.unfinished, record)
+		}
+	}
+
+	compactPath := logPath + ".compact"
+	compacted, err := os.OpenFile(compactPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(compacted)
+	for _, record := range pq.unfinished {
+		if err = encoder.Encode(record); err != nil {
+			compacted.Close()
+			return err
+		}
+	}
+
+	if err = compacted.Sync(); err != nil {
+		compacted.Close()
+		return err
+	}
+
+	if err = compacted.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(compactPath, logPath); err != nil {
+		return err
+	}
+
+	pq.file, err = os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := pq.file.Stat()
+	if err != nil {
+		pq.file.Close()
+		return err
+	}
+
+	pq.offset = info.Size()
+
+	return nil
+}
+
+// readPersistentLog returns the records of the log.  A line that cannot be
+// read as a record is skipped, it was either being written when the process
+// ended or has been damaged, and the records after it are still read
+func readPersistentLog(logPath string) ([]*persistentRecord, error) {
+	file, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	retVal := make([]*persistentRecord, 0)
+
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			record := &persistentRecord{}
+
+			if json.Unmarshal(line, record) == nil {
+				retVal = append(retVal, record)
+			}
+		}
+
+		if readErr == io.EOF {
+			return retVal, nil
+		}
+	}
+}
+
+// writeLocked appends the record to the log.  A failed write may have left
+// part of the record in the log, so the log is truncated back to the end of
+// the last record.  If that fails the next record starts on a new line
+// instead.  Must have mutex held
+func (pq *persistentFunctionQueue) writeLocked(record *persistentRecord) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	line := append(raw, '\n')
+	if pq.damaged {
+		line = append([]byte{'\n'}, line...)
+	}
+
+	n, err := pq.file.Write(line)
+	if err != nil {
+		pq.discardPartialWriteLocked()
+		return err
+	}
+
+	if pq.damaged {
+		// Where the partial record ended is not known
+		info, statErr := pq.file.Stat()
+		if statErr == nil {
+			pq.offset = info.Size()
+			pq.damaged = false
+		}
+	} else {
+		pq.offset += int64(n)
+	}
+
+	return pq.file.Sync()
+}
+
+// discardPartialWriteLocked truncates the log back to the end of the last
+// record written.  Must have mutex held
+func (pq *persistentFunctionQueue) discardPartialWriteLocked() {
+	if pq.damaged {
+		return
+	}
+
+	if err := pq.file.Truncate(pq.offset); err != nil {
+		pq.damaged = true
+	}
+}
+
+// RegisterHandler registers a function under the given name
+func (pq *persistentFunctionQueue) RegisterHandler(name string, handler interface{}) error {
+	typ := reflect.TypeOf(handler)
+	if typ == nil || typ.Kind() != reflect.Func {
+		return fmt.Errorf("handler %s must be a function", name)
+	}
+	if typ.IsVariadic() {
+		return fmt.Errorf("handler %s may not be variadic", name)
+	}
+
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	pq.handlers[name] = handler
+
+	return nil
+}
+
+// Enqueue queues a call to the handler named by userCall, which must be a string
+func (pq *persistentFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	if userCall == nil {
+		return nil
+	}
+
+	name, ok := userCall.(string)
+	if !ok {
+		return fmt.Errorf("persistent queue can only enqueue handler names, got %T", userCall)
+	}
+
+	return pq.EnqueueTask(name, args...)
+}
+
+// EnqueueTask queues a call to the named handler with the given arguments
+func (pq *persistentFunctionQueue) EnqueueTask(name string, args ...interface{}) error {
+	rawArgs := make([]json.RawMessage, len(args))
+	for index, arg := range args {
+		raw, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+
+		rawArgs[index] = raw
+	}
+
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if pq.closed {
+		return ErrQueueClosed
+	}
+
+	if uint32(len(pq.queue)) >= pq.capacity {
+		return ErrAtCapacity
+	}
+
+	record := &persistentRecord{
+		Op:      persistentEnqueue,
+		ID:      pq.nextID,
+		Handler: name,
+		Args:    rawArgs,
+	}
+
+	descriptor, err := pq.newDescriptorLocked(record)
+	if err != nil {
+		return err
+	}
+
+	if err = pq.writeLocked(record); err != nil {
+		return err
+	}
+
+	pq.nextID++
+
+	pq.addLocked(descriptor)
+
+	return nil
+}
+
+// Replay queues the unacknowledged tasks of the previous log
+func (pq *persistentFunctionQueue) Replay() (int, error) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if pq.closed {
+		return 0, ErrQueueClosed
+	}
+
+	replayed := 0
+	for len(pq.unfinished) > 0 {
+		descriptor, err := pq.newDescriptorLocked(pq.unfinished[0])
+		if err != nil {
+			return replayed, err
+		}
+
+		pq.unfinished = pq.unfinished[1:]
+
+		pq.addLocked(descriptor)
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// newDescriptorLocked decodes the arguments of the record for its handler and
+// wraps the handler so the record is acknowledged once the handler returns normally.
+// Must have mutex held
+func (pq *persistentFunctionQueue) newDescriptorLocked(record *persistentRecord) (*FunctionDescriptor, error) {
+	handler, found := pq.handlers[record.Handler]
+	if !found {
+		return nil, ErrUnknownHandler
+	}
+
+	typ := reflect.TypeOf(handler)
+	if typ.NumIn() != len(record.Args) {
+		return nil, fmt.Errorf("handler %s has %d parameters, task has %d arguments",
+			record.Handler, typ.NumIn(), len(record.Args))
+	}
+
+	args := make([]interface{}, len(record.Args))
+	for index, raw := range record.Args {
+		value := reflect.New(typ.In(index))
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return nil, err
+		}
+
+		args[index] = value.Elem().Interface()
+	}
+
+	id := record.ID
+	handlerValue := reflect.ValueOf(handler)
+	wrapper := reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		// A handler that panics is not acknowledged, so it can be replayed
+		results := handlerValue.Call(in)
+
+		pq.ack(id)
+
+		return results
+	})
+
+	return &FunctionDescriptor{
+		UserCall:     wrapper.Interface(),
+		Args:         args,
+		EnqueuedTime: time.Now(),
+	}, nil
+}
+
+// addLocked must have mutex held
+func (pq *persistentFunctionQueue) addLocked(descriptor *FunctionDescriptor) {
+	pq.queue = append(pq.queue, descriptor)
+
+	pq.cond.Broadcast()
+	if pq.changer != nil {
+		go pq.changer(pq)
+	}
+}
+
+// ack writes the acknowledgement of the task to the log.  Tasks
+// that finish after the queue is closed are not acknowledged.  An
+// error writing the acknowledgement is placed on the error queue
+func (pq *persistentFunctionQueue) ack(id uint64) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if pq.closed {
+		return
+	}
+
+	err := pq.writeLocked(&persistentRecord{
+		Op: persistentAck,
+		ID: id,
+	})
+	if err != nil && pq.errorQueue != nil {
+		pq.errorQueue.Enqueue(newErrorinformation(GetGoethe().GetThreadID(), err))
+	}
+}
+
+// Dequeue returns a task to be run, waiting the given duration.  If there
+// is no task within the given duration the error returned will be ErrEmptyQueue
+func (pq *persistentFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	if duration < 0 {
+		duration = 0
+	}
+
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	found := waitFor(pq.cond, duration, func() bool {
+		return len(pq.queue) > 0
+	})
+	if !found {
+		return nil, ErrEmptyQueue
+	}
+
+	return pq.dequeueLocked(), nil
+}
+
+// DequeueContext returns a task to be run, waiting until one is available
+// or the context is done.  If the context is done before a task is
+// available the error of the context is returned
+func (pq *persistentFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if len(pq.queue) <= 0 {
+		closer := broadcastOnDone(ctx, pq.cond)
+		defer closer.Close()
+
+		for len(pq.queue) <= 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			pq.cond.Wait()
+		}
+	}
+
+	return pq.dequeueLocked(), nil
+}
+
+// dequeueLocked must have mutex held and the queue must not be empty
+func (pq *persistentFunctionQueue) dequeueLocked() *FunctionDescriptor {
+	retVal := pq.queue[0]
+	pq.queue[0] = nil
+	pq.queue = pq.queue[1:]
+
+	if pq.changer != nil {
+		go pq.changer(pq)
+	}
+
+	return retVal
+}
+
+// SetErrorQueue sets the queue on which errors writing the
+// acknowledgement of a task to the log are placed
+func (pq *persistentFunctionQueue) SetErrorQueue(errorQueue ErrorQueue) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	pq.errorQueue = errorQueue
+}
+
+// GetErrorQueue returns the error queue of this queue, which may be nil
+func (pq *persistentFunctionQueue) GetErrorQueue() ErrorQueue {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	return pq.errorQueue
+}
+
+// GetDirectory returns the directory holding the log of this queue
+func (pq *persistentFunctionQueue) GetDirectory() string {
+	return pq.directory
+}
+
+// Close closes the log of this queue
+func (pq *persistentFunctionQueue) Close() error {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if pq.closed {
+		return nil
+	}
+
+	pq.closed = true
+
+	return pq.file.Close()
+}
+
+// GetCapacity gets the capacity of this queue
+func (pq *persistentFunctionQueue) GetCapacity() uint32 {
+	return pq.capacity
+}
+
+// GetSize returns the number of items currently in the queue
+func (pq *persistentFunctionQueue) GetSize() int {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	return len(pq.queue)
+}
+
+// IsEmpty Returns true if this queue is currently empty
+func (pq *persistentFunctionQueue) IsEmpty() bool {
+	return pq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be
+// called whenever an enqueue or dequeue changes
+// the size of queue
+func (pq *persistentFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	pq.changer = ch
+}
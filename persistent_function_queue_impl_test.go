@@ -0,0 +1,149 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPersistentFunctionQueueAckErrorReported(t *testing.T) {
+	directory, err := ioutil.TempDir("", "goethe-persistent")
+	if err != nil {
+		t.Errorf("could not create directory %v", err)
+		return
+	}
+	defer os.RemoveAll(directory)
+
+	queue, err := NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not create queue %v", err)
+		return
+	}
+
+	errorQueue := NewBoundedErrorQueue(10)
+	queue.SetErrorQueue(errorQueue)
+
+	queue.RegisterHandler("echo", func(message string) {})
+	queue.EnqueueTask("echo", "hello")
+
+	descriptor, _ := queue.Dequeue(0)
+
+	// Writing the acknowledgement fails
+	queue.(*persistentFunctionQueue).file.Close()
+
+	descriptor.UserCall.(func(string))(descriptor.Args[0].(string))
+
+	info, found := errorQueue.Dequeue()
+	if !found {
+		t.Errorf("failure to write the acknowledgement was not reported")
+		return
+	}
+
+	if info.GetError() == nil {
+		t.Errorf("expected the error writing the log")
+	}
+}
+
+func TestPersistentFunctionQueuePartialWriteDiscarded(t *testing.T) {
+	for _, truncateFails := range []bool{false, true} {
+		directory, err := ioutil.TempDir("", "goethe-persistent")
+		if err != nil {
+			t.Errorf("could not create directory %v", err)
+			return
+		}
+		defer os.RemoveAll(directory)
+
+		queue, err := NewPersistentFunctionQueue(directory, 10)
+		if err != nil {
+			t.Errorf("could not create queue %v", err)
+			return
+		}
+
+		queue.RegisterHandler("echo", func(message string) {})
+		queue.EnqueueTask("echo", "first")
+
+		pq := queue.(*persistentFunctionQueue)
+
+		// A write that failed part way through the record
+		pq.mux.Lock()
+		pq.file.Write([]byte(`{"op":"enqueue","id":1,"hand`))
+		if truncateFails {
+			pq.damaged = true
+		} else {
+			pq.discardPartialWriteLocked()
+		}
+		pq.mux.Unlock()
+
+		queue.EnqueueTask("echo", "second")
+		queue.EnqueueTask("echo", "third")
+		queue.Close()
+
+		replayed, err := NewPersistentFunctionQueue(directory, 10)
+		if err != nil {
+			t.Errorf("could not reopen queue %v", err)
+			return
+		}
+
+		replayed.RegisterHandler("echo", func(message string) {})
+
+		count, err := replayed.Replay()
+		if err != nil || count != 3 {
+			t.Errorf("expected three tasks replayed with truncate failing %v, got %d/%v",
+				truncateFails, count, err)
+		}
+
+		for _, expected := range []string{"first", "second", "third"} {
+			descriptor, err := replayed.Dequeue(0)
+			if err != nil {
+				t.Errorf("could not dequeue %s %v", expected, err)
+				break
+			}
+
+			if descriptor.Args[0] != expected {
+				t.Errorf("expected %s, got %v", expected, descriptor.Args[0])
+			}
+		}
+
+		replayed.Close()
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type phaser struct {
+	parent *StandardThreadUtilities
+
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	phase      int32
+	registered int32
+	arrivals   []int64
+	terminated bool
+	onAdvance  func(int32, []int64)
+}
+
+func newPhaser(par *StandardThreadUtilities, parties int32, onAdvance func(int32, []int64)) (Phaser, error) {
+	if parties < 0 {
+		return nil, ErrIllegalCount
+	}
+
+	retVal := &phaser{
+		parent:     par,
+		registered: parties,
+		arrivals:   make([]int64, 0),
+		onAdvance:  onAdvance,
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal, nil
+}
+
+func (p *phaser) Register() (int32, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.terminated {
+		return p.phase, ErrPhaserTerminated
+	}
+
+	p.registered++
+
+	return p.phase, nil
+}
+
+func (p *phaser) Arrive() (int32, error) {
+	return p.arrive(false)
+}
+
+func (p *phaser) ArriveAndDeregister() (int32, error) {
+	return p.arrive(true)
+}
+
+func (p *phaser) arrive(deregister bool) (int32, error) {
+	tid := p.parent.GetThreadID()
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.terminated {
+		return p.phase, ErrPhaserTerminated
+	}
+
+	if int32(len(p.arrivals)) >= p.registered {
+		return p.phase, ErrPhaserNoParties
+	}
+
+	phase := p.phase
+
+	if deregister {
+		// A party that leaves only lowers the number needed to complete the phase
+		p.registered--
+	} else {
+		p.arrivals = append(p.arrivals, tid)
+	}
+
+	if int32(len(p.arrivals)) >= p.registered {
+		p.advanceLocked()
+	}
+
+	return phase, nil
+}
+
+// advanceLocked must have mutex held
+func (p *phaser) advanceLocked() {
+	if p.onAdvance != nil {
+		p.onAdvance(p.phase, append([]int64{}, p.arrivals...))
+	}
+
+	if p.phase == math.MaxInt32 {
+		p.phase = 0
+	} else {
+		p.phase++
+	}
+
+	p.arrivals = make([]int64, 0)
+
+	if p.registered <= 0 {
+		p.terminated = true
+	}
+
+	p.cond.Broadcast()
+}
+
+func (p *phaser) ArriveAndAwaitAdvance(d time.Duration) (int32, error) {
+	if d < -1 {
+		return 0, ErrIllegalDuration
+	}
+
+	phase, err := p.Arrive()
+	if err != nil {
+		return phase, err
+	}
+
+	return p.AwaitAdvance(phase, d)
+}
+
+func (p *phaser) AwaitAdvance(phase int32, d time.Duration) (int32, error) {
+	if d < -1 {
+		return 0, ErrIllegalDuration
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	advanced := waitFor(p.cond, d, func() bool {
+		return p.phase != phase || p.terminated
+	})
+	if !advanced {
+		return p.phase, ErrTimeout
+	}
+
+	if p.phase == phase && p.terminated {
+		return p.phase, ErrPhaserTerminated
+	}
+
+	return p.phase, nil
+}
+
+func (p *phaser) GetPhase() int32 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.phase
+}
+
+func (p *phaser) GetRegisteredParties() int32 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.registered
+}
+
+func (p *phaser) GetArrivedParties() int32 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return int32(len(p.arrivals))
+}
+
+func (p *phaser) GetArrivals() []int64 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return append([]int64{}, p.arrivals...)
+}
+
+func (p *phaser) IsTerminated() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.terminated
+}
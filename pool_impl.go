@@ -41,6 +41,7 @@
 package goethe
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sync"
@@ -51,18 +52,33 @@ type threadPool struct {
 	mux                    sync.Mutex
 	name                   string
 	started, closed        bool
+	draining               bool
 	minThreads, maxThreads int32
 	idleDecay              time.Duration
 	functionalQueue        FunctionQueue
 	errorQueue             ErrorQueue
 	parent                 *StandardThreadUtilities
+	panicPolicy            PanicPolicy
+	rejectionPolicy        RejectionPolicy
+	blockTimeout           time.Duration
+	rejectionHandler       func(*FunctionDescriptor)
+	batchSize              int32
 
 	currentThreads int32
 	threadState    map[int64]int
+	terminatedCond *sync.Cond
+	roomCond       *sync.Cond
+	queueChanges   uint64
+	stopContext    context.Context
+	stopCancel     context.CancelFunc
+	wakeContext    context.Context
+	wakeCancel     context.CancelFunc
 	closeChannel   chan bool
 	decayChannel   chan bool
 	changeChannel  chan int
 	decayTimer     Timer
+
+	statistics PoolStatistics
 }
 
 // states for each thread in the pool
@@ -102,11 +118,21 @@ func newThreadPool(par *StandardThreadUtilities, name string, min, max int32, id
 		errorQueue:      eq,
 		threadState:     make(map[int64]int),
 		parent:          par,
+		panicPolicy:     par.GetPanicPolicy(),
+		batchSize:       1,
 		closeChannel:    make(chan bool),
 		decayChannel:    make(chan bool),
 		changeChannel:   make(chan int),
 	}
 
+	retVal.statistics.QueueWaitTime = newHistogram()
+	retVal.statistics.ExecutionTime = newHistogram()
+
+	retVal.terminatedCond = sync.NewCond(&retVal.mux)
+	retVal.roomCond = sync.NewCond(&retVal.mux)
+	retVal.stopContext, retVal.stopCancel = context.WithCancel(context.Background())
+	retVal.wakeContext, retVal.wakeCancel = context.WithCancel(retVal.stopContext)
+
 	timer, err := par.ScheduleWithFixedDelay(0, 1*time.Minute,
 		retVal.errorQueue, retVal.ringBell)
 	if err != nil {
@@ -115,6 +141,10 @@ func newThreadPool(par *StandardThreadUtilities, name string, min, max int32, id
 
 	retVal.decayTimer = timer
 
+	// Installed before Start so that Submit calls blocked by RejectBlock
+	// notice room freed on the FunctionQueue of a pool that is not started
+	fq.SetStateChangeCallback(retVal.functionalQueueChanged)
+
 	return retVal, nil
 }
 
@@ -149,12 +179,10 @@ func (threadPool *threadPool) Start() error {
 
 	var lcv int32
 	for lcv = 0; lcv < threadPool.minThreads; lcv++ {
-		goether.Go(threadRunner, threadPool)
-		threadPool.currentThreads++
+		threadPool.startThreadLocked()
 	}
 
 	goether.Go(threadPool.monitor)
-	threadPool.functionalQueue.SetStateChangeCallback(threadPool.functionalQueueChanged)
 
 	threadPool.started = true
 
@@ -166,6 +194,11 @@ func (threadPool *threadPool) functionalQueueChanged(fq FunctionQueue) {
 		recover()
 	}()
 
+	threadPool.mux.Lock()
+	threadPool.queueChanges++
+	threadPool.roomCond.Broadcast()
+	threadPool.mux.Unlock()
+
 	if threadPool.IsClosed() {
 		return
 	}
@@ -181,17 +214,86 @@ func (threadPool *threadPool) GetName() string {
 }
 
 func (threadPool *threadPool) GetMinThreads() int32 {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
 	return threadPool.minThreads
 }
 
 func (threadPool *threadPool) GetMaxThreads() int32 {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
 	return threadPool.maxThreads
 }
 
 func (threadPool *threadPool) GetIdleDecayDuration() time.Duration {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
 	return threadPool.idleDecay
 }
 
+func (threadPool *threadPool) SetMinThreads(min int32) error {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	if min < 0 {
+		return fmt.Errorf("minimum thread count less than zero %d", min)
+	}
+	if min > threadPool.maxThreads {
+		return fmt.Errorf("minimum (%d) is greater than maximum (%d)", min, threadPool.maxThreads)
+	}
+
+	threadPool.minThreads = min
+
+	threadPool.wakeThreadsLocked()
+	threadPool.monitorOnceLocked()
+
+	return nil
+}
+
+func (threadPool *threadPool) SetMaxThreads(max int32) error {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	if max < 1 {
+		return fmt.Errorf("maximum thread count less than one %d", max)
+	}
+	if threadPool.minThreads > max {
+		return fmt.Errorf("minimum (%d) is greater than maximum (%d)", threadPool.minThreads, max)
+	}
+
+	threadPool.maxThreads = max
+
+	threadPool.wakeThreadsLocked()
+	threadPool.monitorOnceLocked()
+
+	return nil
+}
+
+func (threadPool *threadPool) SetIdleDecayDuration(idle time.Duration) error {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	if idle < 0 {
+		return ErrIllegalDuration
+	}
+
+	threadPool.idleDecay = idle
+
+	threadPool.wakeThreadsLocked()
+
+	return nil
+}
+
+// wakeThreadsLocked wakes up the threads waiting on the function queue so
+// that they notice the new settings of the pool.  Must have mutex held
+func (threadPool *threadPool) wakeThreadsLocked() {
+	threadPool.wakeCancel()
+	threadPool.wakeContext, threadPool.wakeCancel = context.WithCancel(threadPool.stopContext)
+}
+
 func (threadPool *threadPool) GetCurrentThreadCount() int32 {
 	threadPool.mux.Lock()
 	defer threadPool.mux.Unlock()
@@ -199,6 +301,20 @@ func (threadPool *threadPool) GetCurrentThreadCount() int32 {
 	return threadPool.currentThreads
 }
 
+func (threadPool *threadPool) SetPanicPolicy(policy PanicPolicy) {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	threadPool.panicPolicy = policy
+}
+
+func (threadPool *threadPool) GetPanicPolicy() PanicPolicy {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	return threadPool.panicPolicy
+}
+
 func (threadPool *threadPool) GetFunctionQueue() FunctionQueue {
 	return threadPool.functionalQueue
 }
@@ -207,6 +323,220 @@ func (threadPool *threadPool) GetErrorQueue() ErrorQueue {
 	return threadPool.errorQueue
 }
 
+func (threadPool *threadPool) Submit(userCall interface{}, args ...interface{}) (Future, error) {
+	return threadPool.submit(nil, userCall, args)
+}
+
+func (threadPool *threadPool) SubmitContext(ctx context.Context, userCall interface{}, args ...interface{}) (Future, error) {
+	return threadPool.submit(ctx, userCall, args)
+}
+
+func (threadPool *threadPool) submit(ctx context.Context, userCall interface{}, args []interface{}) (Future, error) {
+	if threadPool.IsClosed() {
+		threadPool.rejected(nil)
+		return nil, ErrPoolClosed
+	}
+
+	arguments, err := getValues(userCall, args)
+	if err != nil {
+		return nil, err
+	}
+
+	future := newFuture(userCall, arguments, nil, threadPool.GetPanicPolicy())
+	if ctx != nil {
+		future.watchContext(ctx)
+	}
+
+	err = threadPool.enqueue(future)
+	if err != nil {
+		return nil, err
+	}
+
+	return future, nil
+}
+
+// enqueue puts the future on the FunctionQueue, applying the
+// rejection policy if the FunctionQueue is at capacity
+func (threadPool *threadPool) enqueue(future *futureImpl) error {
+	err := threadPool.functionalQueue.Enqueue(runFuture, future)
+	if err == nil {
+		return nil
+	}
+
+	descriptor := &FunctionDescriptor{
+		UserCall:     runFuture,
+		Args:         []interface{}{future},
+		EnqueuedTime: time.Now(),
+	}
+
+	if err != ErrAtCapacity {
+		future.Cancel()
+		threadPool.rejected(descriptor)
+		return err
+	}
+
+	policy, blockTimeout := threadPool.GetRejectionPolicy()
+
+	switch policy {
+	case RejectCallerRuns:
+		threadPool.rejected(descriptor)
+
+		// The error goes to the ErrorQueue as it would from a thread of the pool
+		if runErr := runFuture(future); runErr != nil && threadPool.errorQueue != nil {
+			threadPool.errorQueue.Enqueue(newErrorinformation(threadPool.parent.GetThreadID(), runErr))
+		}
+
+		return nil
+	case RejectDiscardOldest:
+		for err == ErrAtCapacity {
+			oldest, dequeueErr := threadPool.functionalQueue.Dequeue(0)
+			if dequeueErr != nil {
+				break
+			}
+
+			cancelDescriptor(oldest)
+			threadPool.rejected(oldest)
+
+			err = threadPool.functionalQueue.Enqueue(runFuture, future)
+		}
+	case RejectDiscardNewest:
+		future.Cancel()
+		threadPool.rejected(descriptor)
+		return nil
+	case RejectBlock:
+		err = threadPool.enqueueWait(future, blockTimeout)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	future.Cancel()
+	threadPool.rejected(descriptor)
+
+	return err
+}
+
+// enqueueWait tries to enqueue the future every time the FunctionQueue
+// changes, until it succeeds or the timeout passes.  The FunctionQueue is
+// not called with mux held since its state change callback takes mux
+func (threadPool *threadPool) enqueueWait(future *futureImpl, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		threadPool.mux.Lock()
+		closed := threadPool.closed
+		changes := threadPool.queueChanges
+		threadPool.mux.Unlock()
+
+		if closed {
+			return ErrPoolClosed
+		}
+
+		err := threadPool.functionalQueue.Enqueue(runFuture, future)
+		if err != ErrAtCapacity {
+			return err
+		}
+
+		remaining := time.Duration(-1)
+		if timeout >= 0 {
+			remaining = time.Until(deadline)
+			if remaining <= 0 {
+				return err
+			}
+		}
+
+		threadPool.mux.Lock()
+		waitFor(threadPool.roomCond, remaining, func() bool {
+			return threadPool.closed || threadPool.queueChanges != changes
+		})
+		threadPool.mux.Unlock()
+	}
+}
+
+// cancelDescriptor cancels the future of a descriptor put on the FunctionQueue by Submit
+func cancelDescriptor(descriptor *FunctionDescriptor) {
+	if len(descriptor.Args) != 1 {
+		return
+	}
+
+	if future, ok := descriptor.Args[0].(*futureImpl); ok {
+		future.Cancel()
+	}
+}
+
+// rejected counts the rejected function and gives it to the rejection handler
+func (threadPool *threadPool) rejected(descriptor *FunctionDescriptor) {
+	threadPool.mux.Lock()
+	threadPool.statistics.RejectedTasks++
+	handler := threadPool.rejectionHandler
+	threadPool.mux.Unlock()
+
+	if handler != nil && descriptor != nil {
+		handler(descriptor)
+	}
+}
+
+func (threadPool *threadPool) SetRejectionPolicy(policy RejectionPolicy, blockTimeout time.Duration) error {
+	if blockTimeout < -1 {
+		return ErrIllegalDuration
+	}
+
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	threadPool.rejectionPolicy = policy
+	threadPool.blockTimeout = blockTimeout
+
+	return nil
+}
+
+func (threadPool *threadPool) GetRejectionPolicy() (RejectionPolicy, time.Duration) {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	return threadPool.rejectionPolicy, threadPool.blockTimeout
+}
+
+func (threadPool *threadPool) SetBatchSize(batchSize int32) error {
+	if batchSize < 1 {
+		return ErrIllegalCount
+	}
+
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	threadPool.batchSize = batchSize
+
+	return nil
+}
+
+func (threadPool *threadPool) GetBatchSize() int32 {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	return threadPool.batchSize
+}
+
+func (threadPool *threadPool) SetRejectionHandler(handler func(*FunctionDescriptor)) {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	threadPool.rejectionHandler = handler
+}
+
+// runFuture is the function placed on the FunctionQueue by Submit.  The
+// error of the user function is returned so that the pool will place it
+// on the error queue
+func runFuture(future *futureImpl) error {
+	future.run()
+
+	return future.GetError()
+}
+
 func (threadPool *threadPool) IsClosed() bool {
 	threadPool.mux.Lock()
 	defer threadPool.mux.Unlock()
@@ -215,14 +545,24 @@ func (threadPool *threadPool) IsClosed() bool {
 }
 
 func (threadPool *threadPool) Close() {
+	threadPool.Shutdown(false)
+}
+
+func (threadPool *threadPool) Shutdown(drain bool) {
 	threadPool.mux.Lock()
 	defer threadPool.mux.Unlock()
 
 	if threadPool.closed {
+		if !drain {
+			// Stop any draining that is in progress
+			threadPool.draining = false
+		}
+
 		return
 	}
 
 	threadPool.closed = true
+	threadPool.draining = drain
 
 	threadPool.functionalQueue.SetStateChangeCallback(nil)
 
@@ -233,6 +573,106 @@ func (threadPool *threadPool) Close() {
 	close(threadPool.closeChannel)
 	close(threadPool.decayChannel)
 	close(threadPool.changeChannel)
+
+	// Wakes up the threads waiting on the function queue
+	threadPool.stopCancel()
+
+	if drain && threadPool.started {
+		// The monitor is gone, so make sure there are threads to do the draining
+		queueSize := int32(threadPool.functionalQueue.GetSize())
+		for threadPool.currentThreads < threadPool.maxThreads && threadPool.currentThreads < queueSize {
+			threadPool.startThreadLocked()
+		}
+	}
+
+	threadPool.terminatedCond.Broadcast()
+	threadPool.roomCond.Broadcast()
+}
+
+func (threadPool *threadPool) ShutdownNow() []*FunctionDescriptor {
+	threadPool.Shutdown(false)
+
+	retVal := make([]*FunctionDescriptor, 0)
+	for {
+		descriptor, err := threadPool.functionalQueue.Dequeue(0)
+		if err != nil {
+			break
+		}
+
+		cancelDescriptor(descriptor)
+
+		retVal = append(retVal, descriptor)
+	}
+
+	if delayQueue, ok := threadPool.functionalQueue.(DelayFunctionQueue); ok {
+		// Jobs that are not yet due are not returned by Dequeue
+		for _, descriptor := range delayQueue.RemoveAll() {
+			cancelDescriptor(descriptor)
+
+			retVal = append(retVal, descriptor)
+		}
+	}
+
+	return retVal
+}
+
+func (threadPool *threadPool) AwaitTermination(d time.Duration) (bool, error) {
+	if d < -1 {
+		return false, ErrIllegalDuration
+	}
+
+	tid := threadPool.parent.GetThreadID()
+
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	if _, found := threadPool.threadState[tid]; found {
+		// Would wait for itself forever
+		return false, ErrNotCalledOnCorrectThread
+	}
+
+	return waitFor(threadPool.terminatedCond, d, threadPool.isTerminatedLocked), nil
+}
+
+func (threadPool *threadPool) GetStatistics() PoolStatistics {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	retVal := threadPool.statistics
+	retVal.QueueWaitTime = threadPool.statistics.QueueWaitTime.copy()
+	retVal.ExecutionTime = threadPool.statistics.ExecutionTime.copy()
+
+	for _, state := range threadPool.threadState {
+		switch state {
+		case WAITING:
+			retVal.IdleThreads++
+		case RUNNING:
+			retVal.ActiveThreads++
+		}
+	}
+
+	return retVal
+}
+
+func (threadPool *threadPool) IsTerminated() bool {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	return threadPool.isTerminatedLocked()
+}
+
+// isTerminatedLocked must have mutex held
+func (threadPool *threadPool) isTerminatedLocked() bool {
+	return threadPool.closed && threadPool.currentThreads <= 0
+}
+
+// getShutdownState returns whether or not the pool is closed and if it is
+// whether or not the function queue should be drained
+func (threadPool *threadPool) getShutdownState() (bool, bool) {
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	return threadPool.closed, threadPool.draining
 }
 
 func (threadPool *threadPool) monitor() {
@@ -258,6 +698,20 @@ func (threadPool *threadPool) monitorOnce() {
 	threadPool.mux.Lock()
 	defer threadPool.mux.Unlock()
 
+	threadPool.monitorOnceLocked()
+}
+
+// monitorOnceLocked must have mutex held
+func (threadPool *threadPool) monitorOnceLocked() {
+	if !threadPool.started || threadPool.closed {
+		return
+	}
+
+	for threadPool.currentThreads < threadPool.minThreads {
+		// Replace threads that have been lost, for example to a panic
+		threadPool.startThreadLocked()
+	}
+
 	if threadPool.currentThreads >= threadPool.maxThreads {
 		// already at limit
 		return
@@ -292,37 +746,118 @@ func (threadPool *threadPool) monitorOnce() {
 
 	for lcv := 0; lcv < numberToAdd; lcv++ {
 		// We have to grow!
-		goether := GetGoethe()
+		threadPool.startThreadLocked()
+	}
+}
 
-		goether.Go(threadRunner, threadPool)
-		threadPool.currentThreads++
+// startThreadLocked must have mutex held
+func (threadPool *threadPool) startThreadLocked() {
+	GetGoethe().Go(threadRunner, threadPool)
+
+	threadPool.currentThreads++
+	threadPool.statistics.ThreadsCreated++
+	if threadPool.currentThreads > threadPool.statistics.PeakThreads {
+		threadPool.statistics.PeakThreads = threadPool.currentThreads
 	}
 }
 
+// recordTask updates the statistics after a function from the queue has been run
+func (threadPool *threadPool) recordTask(descriptor *FunctionDescriptor, startTime time.Time, err error) {
+	finishTime := time.Now()
+
+	threadPool.mux.Lock()
+	defer threadPool.mux.Unlock()
+
+	if isCancelledFuture(descriptor) {
+		// The function of the future never ran
+		threadPool.statistics.CancelledTasks++
+		return
+	}
+
+	if err != nil {
+		threadPool.statistics.FailedTasks++
+	} else {
+		threadPool.statistics.CompletedTasks++
+	}
+
+	if !descriptor.EnqueuedTime.IsZero() {
+		threadPool.statistics.QueueWaitTime.record(startTime.Sub(descriptor.EnqueuedTime))
+	}
+	threadPool.statistics.ExecutionTime.record(finishTime.Sub(startTime))
+}
+
+// isCancelledFuture returns true if the descriptor is one placed on the
+// FunctionQueue by Submit whose future was cancelled before it started.
+// A future can only be cancelled while it is pending
+func isCancelledFuture(descriptor *FunctionDescriptor) bool {
+	if len(descriptor.Args) != 1 {
+		return false
+	}
+
+	future, ok := descriptor.Args[0].(*futureImpl)
+	if !ok {
+		return false
+	}
+
+	return future.IsCancelled()
+}
+
 func threadRunner(threadPool *threadPool) {
 	goether := GetGoethe()
 	tid := goether.GetThreadID()
 
-	defer deleteMapTid(threadPool, tid)
+	decayed := false
+	defer func() {
+		threadPool.mux.Lock()
+		defer threadPool.mux.Unlock()
 
-	for {
-		if threadPool.IsClosed() {
-			threadPool.mux.Lock()
+		delete(threadPool.threadState, tid)
+		if !decayed {
+			// Also reached if user code panicked with the PanicRepanic policy
 			threadPool.currentThreads--
-			threadPool.mux.Unlock()
+		}
+
+		threadPool.terminatedCond.Broadcast()
+	}()
+
+	for {
+		closed, draining := threadPool.getShutdownState()
+		if closed && !draining {
+			return
+		}
 
+		if threadPool.decayIfAboveMax() {
+			decayed = true
 			return
 		}
 
 		changeMapState(threadPool, tid, WAITING)
 
-		descriptor, err := threadPool.functionalQueue.Dequeue(threadPool.idleDecay)
+		var descriptor *FunctionDescriptor
+		var err error
+		if closed {
+			descriptor, err = threadPool.functionalQueue.Dequeue(0)
+			if err != nil {
+				// Drained
+				return
+			}
+		} else {
+			descriptor, err = threadPool.dequeue()
+		}
+
 		if err != nil {
-			if err == ErrEmptyQueue {
+			if err == context.Canceled {
+				// The pool has been shutdown or its settings have changed
+				continue
+			}
+
+			if err == ErrEmptyQueue || err == context.DeadlineExceeded {
 				threadPool.mux.Lock()
 				if threadPool.currentThreads > threadPool.minThreads {
 					// Reduce size of thread pool, but not below minimum
 					threadPool.currentThreads--
+					threadPool.statistics.ThreadsDecayed++
+					decayed = true
 
 					threadPool.mux.Unlock()
 					return
@@ -330,36 +865,100 @@ func threadRunner(threadPool *threadPool) {
 				threadPool.mux.Unlock()
 			} else {
 				// Todo: log this error or something?
-				threadPool.mux.Lock()
-				threadPool.currentThreads--
-				threadPool.mux.Unlock()
-
 				return
 			}
 		} else {
 			changeMapState(threadPool, tid, RUNNING)
 
-			argsAsVals, err := getValues(descriptor.UserCall, descriptor.Args)
-			if err != nil {
-				// Todo: log this error or something?
-				return
+			threadPool.runDescriptor(tid, descriptor)
+			for _, extra := range threadPool.dequeueRestOfBatch() {
+				threadPool.runDescriptor(tid, extra)
 			}
+		}
+	}
+}
+
+// runDescriptor runs a function taken from the function queue
+func (threadPool *threadPool) runDescriptor(tid int64, descriptor *FunctionDescriptor) {
+	startTime := time.Now()
 
-			invoke(descriptor.UserCall, argsAsVals, threadPool.errorQueue)
+	argsAsVals, err := getValues(descriptor.UserCall, descriptor.Args)
+	if err != nil {
+		if threadPool.errorQueue != nil {
+			threadPool.errorQueue.Enqueue(newErrorinformation(tid, err))
 		}
+
+		threadPool.recordTask(descriptor, startTime, err)
+		return
 	}
+
+	_, err = invoke(descriptor.UserCall, argsAsVals, threadPool.errorQueue, threadPool.GetPanicPolicy())
+
+	threadPool.recordTask(descriptor, startTime, err)
 }
 
-func changeMapState(threadPool *threadPool, tid int64, newState int) {
+// dequeueRestOfBatch takes, without waiting, the rest of the batch of a
+// thread that has just dequeued a function.  Returns nil if the batch size
+// is one or the function queue does not support batches
+func (threadPool *threadPool) dequeueRestOfBatch() []*FunctionDescriptor {
+	batchSize := threadPool.GetBatchSize()
+	if batchSize <= 1 {
+		return nil
+	}
+
+	batchQueue, ok := threadPool.functionalQueue.(BatchFunctionQueue)
+	if !ok {
+		return nil
+	}
+
+	retVal, err := batchQueue.DequeueBatch(int(batchSize-1), 0)
+	if err != nil {
+		return nil
+	}
+
+	return retVal
+}
+
+// dequeue waits the idle decay duration for a function from the function
+// queue.  If the function queue supports it the wait is cut short when the
+// pool is shutdown or its settings change, in which case context.Canceled
+// is returned
+func (threadPool *threadPool) dequeue() (*FunctionDescriptor, error) {
+	threadPool.mux.Lock()
+	idleDecay := threadPool.idleDecay
+	wakeContext := threadPool.wakeContext
+	threadPool.mux.Unlock()
+
+	contextQueue, ok := threadPool.functionalQueue.(ContextFunctionQueue)
+	if !ok {
+		return threadPool.functionalQueue.Dequeue(idleDecay)
+	}
+
+	ctx, cancel := context.WithTimeout(wakeContext, idleDecay)
+	defer cancel()
+
+	return contextQueue.DequeueContext(ctx)
+}
+
+// decayIfAboveMax removes the calling thread from the count of threads if
+// the pool has more threads than the maximum, returning true if it did
+func (threadPool *threadPool) decayIfAboveMax() bool {
 	threadPool.mux.Lock()
 	defer threadPool.mux.Unlock()
 
-	threadPool.threadState[tid] = newState
+	if threadPool.currentThreads <= threadPool.maxThreads {
+		return false
+	}
+
+	threadPool.currentThreads--
+	threadPool.statistics.ThreadsDecayed++
+
+	return true
 }
 
-func deleteMapTid(threadPool *threadPool, tid int64) {
+func changeMapState(threadPool *threadPool, tid int64, newState int) {
 	threadPool.mux.Lock()
 	defer threadPool.mux.Unlock()
 
-	delete(threadPool.threadState, tid)
+	threadPool.threadState[tid] = newState
 }
@@ -0,0 +1,374 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"github.com/jwells131313/goethe/queues"
+	"sort"
+	"sync"
+	"time"
+)
+
+type priorityFunctionQueue struct {
+	mux     sync.Mutex
+	cond    *sync.Cond
+	changer func(queue FunctionQueue)
+
+	capacity   uint32
+	comparator queues.Comparator
+	aging      time.Duration
+
+	// entries is ordered by priority.  While aging is on every entry is
+	// also on the fifo in the order it was enqueued, and an entry taken
+	// from one of them is removed from the other
+	entries  priorityHeap
+	fifo     *list.List
+	sequence uint64
+}
+
+type priorityEntry struct {
+	priority   interface{}
+	sequence   uint64
+	descriptor *FunctionDescriptor
+
+	// index is the position of the entry in the heap and
+	// element is the entry on the fifo, nil while aging is off
+	index   int
+	element *list.Element
+}
+
+// priorityHeap is a heap.Interface with the entry to be returned first on top
+type priorityHeap struct {
+	entries []*priorityEntry
+	compare func(a *priorityEntry, b *priorityEntry) int
+}
+
+// NewPriorityFunctionQueue creates a new function queue with the given capacity
+// that returns functions in the order determined by the comparator applied to
+// their priorities.  The comparator is never called with a nil priority.  If the
+// comparator is nil all priorities are treated as equal
+func NewPriorityFunctionQueue(userCapacity uint32, comparator queues.Comparator) PriorityFunctionQueue {
+	retVal := &priorityFunctionQueue{
+		capacity:   userCapacity,
+		comparator: comparator,
+	}
+
+	retVal.entries = priorityHeap{
+		entries: make([]*priorityEntry, 0),
+		compare: retVal.compareEntries,
+	}
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal
+}
+
+// compareEntries orders entries by priority with nil priorities last,
+// and by the order in which they were enqueued for equal priorities
+func (pq *priorityFunctionQueue) compareEntries(aEntry *priorityEntry, bEntry *priorityEntry) int {
+	result := 0
+	switch {
+	case aEntry.priority == nil && bEntry.priority == nil:
+		result = 0
+	case aEntry.priority == nil:
+		result = -1
+	case bEntry.priority == nil:
+		result = 1
+	case pq.comparator != nil:
+		result = pq.comparator(aEntry.priority, bEntry.priority)
+	}
+
+	if result != 0 {
+		return result
+	}
+
+	if aEntry.sequence < bEntry.sequence {
+		return 1
+	}
+	if aEntry.sequence > bEntry.sequence {
+		return -1
+	}
+
+	return 0
+}
+
+// Enqueue queues a function to be run in the pool with a nil priority.
+// Returns ErrAtCapacity if the queue is currently at capacity
+func (pq *priorityFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	return pq.EnqueueWithPriority(nil, userCall, args...)
+}
+
+// EnqueueWithPriority queues a function with the given priority to be run
+// in the pool.  Returns ErrAtCapacity if the queue is currently at capacity
+func (pq *priorityFunctionQueue) EnqueueWithPriority(priority interface{}, userCall interface{}, args ...interface{}) error {
+	if userCall == nil {
+		return nil
+	}
+
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if uint32(pq.entries.Len()) >= pq.capacity {
+		return ErrAtCapacity
+	}
+
+	descriptor := &FunctionDescriptor{
+		UserCall:     userCall,
+		Args:         make([]interface{}, len(args)),
+		EnqueuedTime: time.Now(),
+	}
+
+	for index, arg := range args {
+		descriptor.Args[index] = arg
+	}
+
+	entry := &priorityEntry{
+		priority:   priority,
+		sequence:   pq.sequence,
+		descriptor: descriptor,
+	}
+	pq.sequence++
+
+	heap.Push(&pq.entries, entry)
+	if pq.fifo != nil {
+		entry.element = pq.fifo.PushBack(entry)
+	}
+
+	pq.cond.Broadcast()
+	if pq.changer != nil {
+		go pq.changer(pq)
+	}
+
+	return nil
+}
+
+// Dequeue returns the function with the highest priority, waiting the
+// given duration.  If there is no message within the given duration
+// return the error returned will be ErrEmptyQueue
+func (pq *priorityFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	if duration < 0 {
+		duration = 0
+	}
+
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	found := waitFor(pq.cond, duration, func() bool {
+		return pq.entries.Len() > 0
+	})
+	if !found {
+		return nil, ErrEmptyQueue
+	}
+
+	return pq.dequeueLocked(), nil
+}
+
+// DequeueContext returns the function with the highest priority, waiting
+// until one is available or the context is done.  If the context is done
+// before a function is available the error of the context is returned
+func (pq *priorityFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	if pq.entries.Len() <= 0 {
+		closer := broadcastOnDone(ctx, pq.cond)
+		defer closer.Close()
+
+		for pq.entries.Len() <= 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			pq.cond.Wait()
+		}
+	}
+
+	return pq.dequeueLocked(), nil
+}
+
+// dequeueLocked must have mutex held and the queue must not be empty
+func (pq *priorityFunctionQueue) dequeueLocked() *FunctionDescriptor {
+	entry := pq.takeAgedLocked()
+	if entry != nil {
+		heap.Remove(&pq.entries, entry.index)
+	} else {
+		entry = heap.Pop(&pq.entries).(*priorityEntry)
+	}
+
+	if entry.element != nil {
+		pq.fifo.Remove(entry.element)
+		entry.element = nil
+	}
+
+	if pq.changer != nil {
+		go pq.changer(pq)
+	}
+
+	return entry.descriptor
+}
+
+// takeAgedLocked returns the oldest entry if it has waited longer than
+// the aging duration, or nil.  Must have mutex held
+func (pq *priorityFunctionQueue) takeAgedLocked() *priorityEntry {
+	if pq.fifo == nil {
+		return nil
+	}
+
+	front := pq.fifo.Front()
+	if front == nil {
+		return nil
+	}
+
+	oldest := front.Value.(*priorityEntry)
+	if time.Since(oldest.descriptor.EnqueuedTime) < pq.aging {
+		return nil
+	}
+
+	return oldest
+}
+
+// SetAgingDuration sets how long a function may wait before it is returned
+// ahead of functions with a higher priority.  0 turns aging off
+func (pq *priorityFunctionQueue) SetAgingDuration(aging time.Duration) error {
+	if aging < 0 {
+		return ErrIllegalDuration
+	}
+
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	pq.aging = aging
+
+	if aging <= 0 {
+		for _, entry := range pq.entries.entries {
+			entry.element = nil
+		}
+
+		pq.fifo = nil
+
+		return nil
+	}
+
+	if pq.fifo == nil {
+		ordered := make([]*priorityEntry, len(pq.entries.entries))
+		copy(ordered, pq.entries.entries)
+
+		sort.Slice(ordered, func(i, j int) bool {
+			return ordered[i].sequence < ordered[j].sequence
+		})
+
+		pq.fifo = list.New()
+		for _, entry := range ordered {
+			entry.element = pq.fifo.PushBack(entry)
+		}
+	}
+
+	return nil
+}
+
+// GetAgingDuration returns the aging duration of this queue
+func (pq *priorityFunctionQueue) GetAgingDuration() time.Duration {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	return pq.aging
+}
+
+// GetCapacity gets the capacity of this queue
+func (pq *priorityFunctionQueue) GetCapacity() uint32 {
+	return pq.capacity
+}
+
+// GetSize returns the number of items currently in the queue
+func (pq *priorityFunctionQueue) GetSize() int {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	return pq.entries.Len()
+}
+
+// IsEmpty Returns true if this queue is currently empty
+func (pq *priorityFunctionQueue) IsEmpty() bool {
+	return pq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be
+// called whenever an enqueue or dequeue changes
+// the size of queue
+func (pq *priorityFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	pq.mux.Lock()
+	defer pq.mux.Unlock()
+
+	pq.changer = ch
+}
+
+func (ph *priorityHeap) Len() int {
+	return len(ph.entries)
+}
+
+func (ph *priorityHeap) Less(i, j int) bool {
+	return ph.compare(ph.entries[i], ph.entries[j]) > 0
+}
+
+func (ph *priorityHeap) Swap(i, j int) {
+	ph.entries[i], ph.entries[j] = ph.entries[j], ph.entries[i]
+	ph.entries[i].index = i
+	ph.entries[j].index = j
+}
+
+func (ph *priorityHeap) Push(raw interface{}) {
+	entry := raw.(*priorityEntry)
+	entry.index = len(ph.entries)
+
+	ph.entries = append(ph.entries, entry)
+}
+
+func (ph *priorityHeap) Pop() interface{} {
+	last := len(ph.entries) - 1
+
+	retVal := ph.entries[last]
+	ph.entries[last] = nil
+	ph.entries = ph.entries[:last]
+
+	return retVal
+}
@@ -0,0 +1,117 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"testing"
+	"time"
+)
+
+func compareIntPriorities(a interface{}, b interface{}) int {
+	return a.(int) - b.(int)
+}
+
+func TestPriorityFunctionQueueStorageIsBounded(t *testing.T) {
+	for _, aging := range []time.Duration{0, time.Hour} {
+		queue := NewPriorityFunctionQueue(10, compareIntPriorities).(*priorityFunctionQueue)
+		queue.SetAgingDuration(aging)
+
+		f := func() {}
+
+		// Starved by every function enqueued after it
+		queue.EnqueueWithPriority(0, f)
+
+		for lcv := 0; lcv < 100000; lcv++ {
+			queue.EnqueueWithPriority(5, f)
+			queue.Dequeue(0)
+		}
+
+		if queue.GetSize() != 1 {
+			t.Errorf("expected size of 1, got %d", queue.GetSize())
+			return
+		}
+
+		if len(queue.entries.entries) != 1 || cap(queue.entries.entries) > 10 {
+			t.Errorf("heap holds %d entries with capacity %d",
+				len(queue.entries.entries), cap(queue.entries.entries))
+			return
+		}
+
+		if aging <= 0 && queue.fifo != nil {
+			t.Errorf("fifo kept with aging off, length %d", queue.fifo.Len())
+			return
+		}
+
+		if aging > 0 && queue.fifo.Len() != 1 {
+			t.Errorf("fifo holds %d entries", queue.fifo.Len())
+			return
+		}
+	}
+}
+
+func TestPriorityFunctionQueueAgedEntryLeavesHeap(t *testing.T) {
+	queue := NewPriorityFunctionQueue(10, compareIntPriorities).(*priorityFunctionQueue)
+
+	f := func() {}
+
+	queue.EnqueueWithPriority(0, f)
+	queue.EnqueueWithPriority(5, f)
+
+	// Turning aging on after the fact puts the queued entries on the fifo
+	queue.SetAgingDuration(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	descriptor, err := queue.Dequeue(0)
+	if err != nil {
+		t.Errorf("unexpected dequeue error %v", err)
+		return
+	}
+
+	if descriptor == nil || len(queue.entries.entries) != 1 || queue.fifo.Len() != 1 {
+		t.Errorf("aged entry was not removed from both structures, heap %d fifo %d",
+			len(queue.entries.entries), queue.fifo.Len())
+		return
+	}
+
+	if queue.entries.entries[0].priority != 5 {
+		t.Errorf("expected the priority 5 entry to remain, got %v", queue.entries.entries[0].priority)
+	}
+}
@@ -88,31 +88,60 @@ func getValues(method interface{}, args []interface{}) ([]reflect.Value, error)
 }
 
 // invoke will call the method with the arguments, and ship any errors
-// returned by the method to the errorQueue (which may be nil)
-func invoke(method interface{}, args []reflect.Value, errorQueue ErrorQueue) {
+// returned by the method to the errorQueue (which may be nil).  All of the
+// values returned by the method are returned along with the first non-nil
+// error returned by the method.  If the method panics the panic is handled
+// with the given policy and the PanicError is returned as the error
+func invoke(method interface{}, args []reflect.Value, errorQueue ErrorQueue,
+	policy PanicPolicy) (results []interface{}, firstError error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		results = nil
+		firstError = globalGoethe.handlePanic(recovered, errorQueue, policy)
+	}()
+
 	val := reflect.ValueOf(method)
 	retVals := val.Call(args)
 
-	if errorQueue != nil {
-		tid := GetGoethe().GetThreadID()
+	results = make([]interface{}, len(retVals))
 
-		// pick first returned error and return it
-		for _, retVal := range retVals {
-			if !retVal.IsNil() && retVal.CanInterface() {
-				// First returned value that is not nill and is an error
-				it := retVal.Type()
-				isAnError := it.Implements(errorInterface)
+	for index, retVal := range retVals {
+		if !retVal.CanInterface() {
+			continue
+		}
 
-				if isAnError {
-					iFace := retVal.Interface()
+		results[index] = retVal.Interface()
 
-					asErr := iFace.(error)
+		if isNilValue(retVal) || !retVal.Type().Implements(errorInterface) {
+			continue
+		}
 
-					errInfo := newErrorinformation(tid, asErr)
+		asErr := results[index].(error)
+		if firstError == nil {
+			firstError = asErr
+		}
 
-					errorQueue.Enqueue(errInfo)
-				}
-			}
+		if errorQueue != nil {
+			errInfo := newErrorinformation(GetGoethe().GetThreadID(), asErr)
+
+			errorQueue.Enqueue(errInfo)
 		}
 	}
+
+	return results, firstError
+}
+
+// isNilValue returns true if the value is of a kind that can be nil and is nil
+func isNilValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice,
+		reflect.UnsafePointer:
+		return val.IsNil()
+	default:
+		return false
+	}
 }
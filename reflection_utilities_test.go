@@ -100,7 +100,7 @@ func TestInvokeWithNil(t *testing.T) {
 	}
 
 	go func() {
-		invoke(bbB, v, nil)
+		invoke(bbB, v, nil, PanicRecover)
 	}()
 
 	r0 := <-rChan
@@ -0,0 +1,173 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"sync"
+)
+
+type ringErrorQueue struct {
+	mux sync.Mutex
+
+	// head is the index of the oldest error in ring
+	ring    []ErrorInformation
+	head    int
+	size    int
+	dropped uint64
+}
+
+// NewRingErrorQueue creates a new error queue that keeps the most recent
+// errors up to the given capacity, overwriting the oldest error when full
+func NewRingErrorQueue(userCapacity uint32) RingErrorQueue {
+	return &ringErrorQueue{
+		ring: make([]ErrorInformation, userCapacity),
+	}
+}
+
+// Enqueue adds an error to the error queue.  If the queue is
+// full the oldest error is overwritten and counted as dropped.
+// Never returns an error
+func (errorq *ringErrorQueue) Enqueue(info ErrorInformation) error {
+	if info == nil {
+		return nil
+	}
+
+	errorq.mux.Lock()
+	defer errorq.mux.Unlock()
+
+	capacity := len(errorq.ring)
+	if capacity == 0 {
+		errorq.dropped++
+		return nil
+	}
+
+	if errorq.size >= capacity {
+		errorq.ring[errorq.head] = info
+		errorq.head = (errorq.head + 1) % capacity
+		errorq.dropped++
+
+		return nil
+	}
+
+	errorq.ring[(errorq.head+errorq.size)%capacity] = info
+	errorq.size++
+
+	return nil
+}
+
+// Dequeue removes ErrorInformation from the pools
+// error queue.  If there were no errors on the queue
+// the second return value is false
+func (errorq *ringErrorQueue) Dequeue() (ErrorInformation, bool) {
+	errorq.mux.Lock()
+	defer errorq.mux.Unlock()
+
+	if errorq.size <= 0 {
+		return nil, false
+	}
+
+	retVal := errorq.ring[errorq.head]
+	errorq.ring[errorq.head] = nil
+	errorq.head = (errorq.head + 1) % len(errorq.ring)
+	errorq.size--
+
+	return retVal, true
+}
+
+// DequeueAll removes every error from the queue and returns
+// them, oldest first.  Returns an empty slice if there are no errors
+func (errorq *ringErrorQueue) DequeueAll() []ErrorInformation {
+	errorq.mux.Lock()
+	defer errorq.mux.Unlock()
+
+	retVal := make([]ErrorInformation, errorq.size)
+	for index := range retVal {
+		ringIndex := (errorq.head + index) % len(errorq.ring)
+
+		retVal[index] = errorq.ring[ringIndex]
+		errorq.ring[ringIndex] = nil
+	}
+
+	errorq.head = 0
+	errorq.size = 0
+
+	return retVal
+}
+
+// Peek returns the most recent error without removing it.  If there
+// were no errors on the queue the second return value is false
+func (errorq *ringErrorQueue) Peek() (ErrorInformation, bool) {
+	errorq.mux.Lock()
+	defer errorq.mux.Unlock()
+
+	if errorq.size <= 0 {
+		return nil, false
+	}
+
+	return errorq.ring[(errorq.head+errorq.size-1)%len(errorq.ring)], true
+}
+
+// GetCapacity returns the number of errors this queue keeps
+func (errorq *ringErrorQueue) GetCapacity() uint32 {
+	return uint32(len(errorq.ring))
+}
+
+// GetDroppedCount returns the number of errors that have been
+// overwritten by newer errors since this queue was created
+func (errorq *ringErrorQueue) GetDroppedCount() uint64 {
+	errorq.mux.Lock()
+	defer errorq.mux.Unlock()
+
+	return errorq.dropped
+}
+
+// GetSize returns the number of items currently in the queue
+func (errorq *ringErrorQueue) GetSize() int {
+	errorq.mux.Lock()
+	defer errorq.mux.Unlock()
+
+	return errorq.size
+}
+
+// IsEmpty Returns true if this queue is currently empty
+func (errorq *ringErrorQueue) IsEmpty() bool {
+	return errorq.GetSize() == 0
+}
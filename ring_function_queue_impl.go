@@ -0,0 +1,326 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ringFunctionQueue is a bounded multi-producer multi-consumer queue based on
+// the array queue of Dmitry Vyukov.  Each cell has a sequence number that tells
+// producers and consumers whether the cell is ready for them, so neither takes
+// a lock.  Only consumers that have to wait for a function use the mutex
+type ringFunctionQueue struct {
+	_          [8]uint64
+	enqueuePos uint64
+	_          [7]uint64
+	dequeuePos uint64
+	_          [7]uint64
+
+	mask  uint64
+	cells []ringCell
+
+	// empty is true for a queue with a capacity of 0,
+	// whose single cell is never used
+	empty bool
+
+	// waiters is the number of consumers waiting on cond
+	waiters int32
+	mux     sync.Mutex
+	cond    *sync.Cond
+
+	// notifyPending is set while a state change notification has been
+	// started but has not yet called the changer
+	changer       atomic.Value
+	notifyPending int32
+}
+
+// maxRingCapacity is the largest power of two that is a uint32
+const maxRingCapacity = 1 << 31
+
+type ringCell struct {
+	sequence   uint64
+	descriptor *FunctionDescriptor
+}
+
+// changerHolder lets a nil changer be stored in an atomic.Value
+type changerHolder struct {
+	changer func(FunctionQueue)
+}
+
+// NewRingFunctionQueue creates a function queue for high throughput that does
+// not take a lock to enqueue or dequeue functions.  Its buffer has a fixed size,
+// so the queue never holds more than that many functions, although each enqueue
+// still allocates the FunctionDescriptor.  The capacity is rounded up to a power
+// of two and is at most 2^31, and a capacity of 0 accepts no functions.  State
+// change callbacks are coalesced, so one call may cover several changes
+func NewRingFunctionQueue(userCapacity uint32) ContextFunctionQueue {
+	if userCapacity > maxRingCapacity {
+		userCapacity = maxRingCapacity
+	}
+
+	size := uint64(1)
+	for size < uint64(userCapacity) {
+		size <<= 1
+	}
+
+	retVal := &ringFunctionQueue{
+		mask:  size - 1,
+		cells: make([]ringCell, size),
+		empty: userCapacity == 0,
+	}
+
+	for index := range retVal.cells {
+		retVal.cells[index].sequence = uint64(index)
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+	retVal.changer.Store(changerHolder{})
+
+	return retVal
+}
+
+// Enqueue queues a function to be run in the pool.  Returns
+// ErrAtCapacity if the queue is currently at capacity
+func (rq *ringFunctionQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	if userCall == nil {
+		return nil
+	}
+
+	if rq.empty {
+		return ErrAtCapacity
+	}
+
+	descriptor := &FunctionDescriptor{
+		UserCall:     userCall,
+		Args:         make([]interface{}, len(args)),
+		EnqueuedTime: time.Now(),
+	}
+
+	for index, arg := range args {
+		descriptor.Args[index] = arg
+	}
+
+	var cell *ringCell
+	pos := atomic.LoadUint64(&rq.enqueuePos)
+	for {
+		cell = &rq.cells[pos&rq.mask]
+		sequence := atomic.LoadUint64(&cell.sequence)
+
+		difference := int64(sequence) - int64(pos)
+		if difference == 0 {
+			if atomic.CompareAndSwapUint64(&rq.enqueuePos, pos, pos+1) {
+				break
+			}
+		} else if difference < 0 {
+			// The consumer of the previous lap has not taken this cell
+			return ErrAtCapacity
+		}
+
+		pos = atomic.LoadUint64(&rq.enqueuePos)
+	}
+
+	cell.descriptor = descriptor
+	atomic.StoreUint64(&cell.sequence, pos+1)
+
+	if atomic.LoadInt32(&rq.waiters) > 0 {
+		rq.mux.Lock()
+		rq.cond.Signal()
+		rq.mux.Unlock()
+	}
+
+	rq.notifyChanged()
+
+	return nil
+}
+
+// tryDequeue takes the next function without waiting
+func (rq *ringFunctionQueue) tryDequeue() (*FunctionDescriptor, bool) {
+	var cell *ringCell
+	pos := atomic.LoadUint64(&rq.dequeuePos)
+	for {
+		cell = &rq.cells[pos&rq.mask]
+		sequence := atomic.LoadUint64(&cell.sequence)
+
+		difference := int64(sequence) - int64(pos+1)
+		if difference == 0 {
+			if atomic.CompareAndSwapUint64(&rq.dequeuePos, pos, pos+1) {
+				break
+			}
+		} else if difference < 0 {
+			// The producer has not filled this cell
+			return nil, false
+		}
+
+		pos = atomic.LoadUint64(&rq.dequeuePos)
+	}
+
+	retVal := cell.descriptor
+	cell.descriptor = nil
+	atomic.StoreUint64(&cell.sequence, pos+rq.mask+1)
+
+	rq.notifyChanged()
+
+	return retVal, true
+}
+
+// Dequeue returns a function to be run, waiting the given
+// duration.  If there is no message within the given
+// duration return the error returned will be ErrEmptyQueue
+func (rq *ringFunctionQueue) Dequeue(duration time.Duration) (*FunctionDescriptor, error) {
+	if retVal, ok := rq.tryDequeue(); ok {
+		return retVal, nil
+	}
+
+	if duration <= 0 {
+		return nil, ErrEmptyQueue
+	}
+
+	rq.mux.Lock()
+	defer rq.mux.Unlock()
+
+	// Producers signal when they see a waiter, and this
+	// tries again after being counted as a waiter
+	atomic.AddInt32(&rq.waiters, 1)
+	defer atomic.AddInt32(&rq.waiters, -1)
+
+	var retVal *FunctionDescriptor
+	found := waitFor(rq.cond, duration, func() bool {
+		var ok bool
+		retVal, ok = rq.tryDequeue()
+		return ok
+	})
+	if !found {
+		return nil, ErrEmptyQueue
+	}
+
+	return retVal, nil
+}
+
+// DequeueContext returns a function to be run, waiting until one
+// is available or the context is done.  If the context is done before
+// a function is available the error of the context is returned
+func (rq *ringFunctionQueue) DequeueContext(ctx context.Context) (*FunctionDescriptor, error) {
+	if retVal, ok := rq.tryDequeue(); ok {
+		return retVal, nil
+	}
+
+	rq.mux.Lock()
+	defer rq.mux.Unlock()
+
+	atomic.AddInt32(&rq.waiters, 1)
+	defer atomic.AddInt32(&rq.waiters, -1)
+
+	closer := broadcastOnDone(ctx, rq.cond)
+	defer closer.Close()
+
+	for {
+		if retVal, ok := rq.tryDequeue(); ok {
+			return retVal, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		rq.cond.Wait()
+	}
+}
+
+// notifyChanged calls the changer unless a call is already pending, in which
+// case the pending call covers this change as well
+func (rq *ringFunctionQueue) notifyChanged() {
+	if rq.changer.Load().(changerHolder).changer == nil {
+		return
+	}
+
+	if !atomic.CompareAndSwapInt32(&rq.notifyPending, 0, 1) {
+		return
+	}
+
+	go func() {
+		// Changes after this point start a new notification
+		atomic.StoreInt32(&rq.notifyPending, 0)
+
+		changer := rq.changer.Load().(changerHolder).changer
+		if changer != nil {
+			changer(rq)
+		}
+	}()
+}
+
+// GetCapacity gets the capacity of this queue
+func (rq *ringFunctionQueue) GetCapacity() uint32 {
+	if rq.empty {
+		return 0
+	}
+
+	return uint32(len(rq.cells))
+}
+
+// GetSize returns the number of items currently in the queue
+func (rq *ringFunctionQueue) GetSize() int {
+	for {
+		dequeuePos := atomic.LoadUint64(&rq.dequeuePos)
+		enqueuePos := atomic.LoadUint64(&rq.enqueuePos)
+
+		if dequeuePos == atomic.LoadUint64(&rq.dequeuePos) {
+			return int(enqueuePos - dequeuePos)
+		}
+	}
+}
+
+// IsEmpty Returns true if this queue is currently empty
+func (rq *ringFunctionQueue) IsEmpty() bool {
+	return rq.GetSize() <= 0
+}
+
+// SetStateChangeCallback sets a function to be
+// called whenever an enqueue or dequeue changes
+// the size of queue
+func (rq *ringFunctionQueue) SetStateChangeCallback(ch func(FunctionQueue)) {
+	rq.changer.Store(changerHolder{
+		changer: ch,
+	})
+}
@@ -0,0 +1,230 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type semaphore struct {
+	parent *StandardThreadUtilities
+
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	available int32
+
+	tracked bool
+	leaks   ErrorQueue
+	held    map[int64]int32
+}
+
+func newSemaphore(par *StandardThreadUtilities, permits int32, tracked bool, leaks ErrorQueue) (Semaphore, error) {
+	if permits < 0 {
+		return nil, ErrIllegalPermits
+	}
+
+	retVal := &semaphore{
+		parent:    par,
+		available: permits,
+		tracked:   tracked,
+		leaks:     leaks,
+		held:      make(map[int64]int32),
+	}
+
+	retVal.cond = sync.NewCond(&retVal.mux)
+
+	return retVal, nil
+}
+
+func (sem *semaphore) Acquire(n int32) error {
+	_, err := sem.TryAcquire(n, -1)
+	return err
+}
+
+func (sem *semaphore) TryAcquire(n int32, d time.Duration) (bool, error) {
+	if n < 0 {
+		return false, ErrIllegalPermits
+	}
+	if d < -1 {
+		return false, ErrIllegalDuration
+	}
+
+	tid := sem.parent.GetThreadID()
+	if sem.tracked {
+		if tid < 0 {
+			return false, ErrNotGoetheThread
+		}
+
+		// Done before taking the mutex since the thread local destroyer
+		// calls back into the semaphore
+		err := sem.registerWithThread()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	sem.mux.Lock()
+	defer sem.mux.Unlock()
+
+	gotIt := waitFor(sem.cond, d, func() bool {
+		return sem.available >= n
+	})
+	if !gotIt {
+		return false, nil
+	}
+
+	sem.available -= n
+	if sem.tracked {
+		sem.held[tid] += n
+	}
+
+	return true, nil
+}
+
+func (sem *semaphore) Release(n int32) error {
+	if n < 0 {
+		return ErrIllegalPermits
+	}
+
+	tid := sem.parent.GetThreadID()
+	if sem.tracked && tid < 0 {
+		return ErrNotGoetheThread
+	}
+
+	sem.mux.Lock()
+	defer sem.mux.Unlock()
+
+	sem.available += n
+
+	if sem.tracked {
+		// A thread may release permits taken by another thread
+		held := sem.held[tid] - n
+		if held > 0 {
+			sem.held[tid] = held
+		} else {
+			delete(sem.held, tid)
+		}
+	}
+
+	sem.cond.Broadcast()
+
+	return nil
+}
+
+func (sem *semaphore) GetAvailablePermits() int32 {
+	sem.mux.Lock()
+	defer sem.mux.Unlock()
+
+	return sem.available
+}
+
+func (sem *semaphore) GetHeldPermits() int32 {
+	tid := sem.parent.GetThreadID()
+
+	sem.mux.Lock()
+	defer sem.mux.Unlock()
+
+	return sem.held[tid]
+}
+
+// registerWithThread remembers this semaphore in the SemaphoreThreadLocal
+// of the calling thread so it can be told when the thread ends
+func (sem *semaphore) registerWithThread() error {
+	tl, err := sem.parent.GetThreadLocal(SemaphoreThreadLocal)
+	if err != nil {
+		return err
+	}
+
+	raw, err := tl.Get()
+	if err != nil {
+		return err
+	}
+
+	semaphores, ok := raw.(map[*semaphore]bool)
+	if !ok {
+		semaphores = make(map[*semaphore]bool)
+		tl.Set(semaphores)
+	}
+
+	semaphores[sem] = true
+
+	return nil
+}
+
+// threadEnded reports any permits the ending thread still holds
+func (sem *semaphore) threadEnded(tid int64) {
+	sem.mux.Lock()
+	defer sem.mux.Unlock()
+
+	leaked, found := sem.held[tid]
+	if !found {
+		return
+	}
+
+	delete(sem.held, tid)
+
+	if sem.leaks != nil {
+		sem.leaks.Enqueue(newErrorinformation(tid, fmt.Errorf("%w: %d permits", ErrPermitsLeaked, leaked)))
+	}
+}
+
+// reportSemaphoreLeaks is the destroyer of the SemaphoreThreadLocal
+func (goth *StandardThreadUtilities) reportSemaphoreLeaks(tl ThreadLocal) error {
+	raw, err := tl.Get()
+	if err != nil {
+		return err
+	}
+
+	semaphores, ok := raw.(map[*semaphore]bool)
+	if !ok {
+		return nil
+	}
+
+	tid := goth.GetThreadID()
+	for sem := range semaphores {
+		sem.threadEnded(tid)
+	}
+
+	return nil
+}
@@ -54,10 +54,11 @@ type sleeper interface {
 }
 
 type sleeperNode struct {
-	ringTime *time.Time
-	cond     *sync.Cond
-	id       uint64
-	closed   bool
+	ringTime  *time.Time
+	cond      *sync.Cond
+	id        uint64
+	closed    bool
+	hasWaiter bool
 }
 
 type sleeperImpl struct {
@@ -114,14 +115,16 @@ func (sleepy *sleeperImpl) sleep(duration time.Duration, cond *sync.Cond, jobNum
 	sleepy.heap.Add(newNode)
 
 	if startNewThread {
-		GetGoethe().Go(sleepy.waiter, duration)
+		newNode.hasWaiter = true
+		GetGoethe().Go(sleepy.waiter, newNode)
 	}
 
 	return newNode
 }
 
-func (sleepy *sleeperImpl) waiter(duration time.Duration) {
-	time.Sleep(duration)
+// waiter rings every node that is due once the given node is due
+func (sleepy *sleeperImpl) waiter(node *sleeperNode) {
+	time.Sleep(time.Until(*node.ringTime))
 
 	sleepy.lock.Lock()
 	defer sleepy.lock.Unlock()
@@ -155,6 +158,12 @@ func (sleepy *sleeperImpl) waiter(duration time.Duration) {
 
 		nextFire = time.Until(*fireTime)
 	}
+
+	// A node added while an earlier one was waiting has no waiter of its own
+	if !sn.hasWaiter || sn == node {
+		sn.hasWaiter = true
+		GetGoethe().Go(sleepy.waiter, sn)
+	}
 }
 
 func (node *sleeperNode) Close() error {
@@ -0,0 +1,97 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import "time"
+
+var (
+	defaultHistogramBounds = []time.Duration{
+		time.Millisecond,
+		10 * time.Millisecond,
+		100 * time.Millisecond,
+		time.Second,
+		10 * time.Second,
+		time.Minute,
+	}
+)
+
+func newHistogram() Histogram {
+	return Histogram{
+		Bounds: defaultHistogramBounds,
+		Counts: make([]uint64, len(defaultHistogramBounds)+1),
+	}
+}
+
+// record adds the duration to the histogram.  Not thread safe
+func (h *Histogram) record(d time.Duration) {
+	index := len(h.Bounds)
+	for lcv, bound := range h.Bounds {
+		if d <= bound {
+			index = lcv
+			break
+		}
+	}
+
+	h.Counts[index]++
+
+	if h.Count == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+
+	h.Count++
+	h.Sum += d
+}
+
+// copy returns a copy of the histogram that does not share the counts
+func (h *Histogram) copy() Histogram {
+	retVal := *h
+
+	retVal.Bounds = make([]time.Duration, len(h.Bounds))
+	copy(retVal.Bounds, h.Bounds)
+
+	retVal.Counts = make([]uint64, len(h.Counts))
+	copy(retVal.Counts, h.Counts)
+
+	return retVal
+}
@@ -0,0 +1,188 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnqueueWait(t *testing.T) {
+	queue := goethe.NewBoundedFunctionQueue(1).(goethe.BatchFunctionQueue)
+
+	f := func() {}
+
+	if err := queue.EnqueueWait(0, f); err != nil {
+		t.Errorf("unexpected enqueue error %v", err)
+	}
+
+	if err := queue.EnqueueWait(20*time.Millisecond, f); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if err := queue.EnqueueWait(-2, f); err != goethe.ErrIllegalDuration {
+		t.Errorf("expected ErrIllegalDuration, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		queue.Dequeue(0)
+	}()
+
+	if err := queue.EnqueueWait(-1, f); err != nil {
+		t.Errorf("enqueue did not wait for room %v", err)
+	}
+
+	if queue.GetSize() != 1 {
+		t.Errorf("expected one function, got %d", queue.GetSize())
+	}
+}
+
+func TestEnqueueAllAndDequeueBatch(t *testing.T) {
+	queue := goethe.NewBoundedFunctionQueue(3).(goethe.BatchFunctionQueue)
+
+	f := func(int) {}
+
+	descriptors := []*goethe.FunctionDescriptor{
+		{UserCall: f, Args: []interface{}{0}},
+		{UserCall: f, Args: []interface{}{1}},
+		{UserCall: nil},
+		{UserCall: f, Args: []interface{}{2}},
+	}
+
+	if err := queue.EnqueueAll(descriptors); err != nil {
+		t.Errorf("unexpected enqueue error %v", err)
+		return
+	}
+
+	if err := queue.EnqueueAll(descriptors[:1]); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if queue.GetSize() != 3 {
+		t.Errorf("expected three functions, got %d", queue.GetSize())
+	}
+
+	batch, err := queue.DequeueBatch(2, 0)
+	if err != nil || len(batch) != 2 {
+		t.Errorf("expected a batch of two, got %d/%v", len(batch), err)
+		return
+	}
+
+	batch2, err := queue.DequeueBatch(10, 0)
+	if err != nil || len(batch2) != 1 {
+		t.Errorf("expected a batch of one, got %d/%v", len(batch2), err)
+		return
+	}
+
+	batch = append(batch, batch2...)
+	for index, descriptor := range batch {
+		if descriptor.Args[0].(int) != index {
+			t.Errorf("expected function %d, got %d", index, descriptor.Args[0])
+		}
+	}
+
+	if _, err = queue.DequeueBatch(1, 10*time.Millisecond); err != goethe.ErrEmptyQueue {
+		t.Errorf("expected ErrEmptyQueue, got %v", err)
+	}
+
+	if _, err = queue.DequeueBatch(0, 0); err != goethe.ErrIllegalCount {
+		t.Errorf("expected ErrIllegalCount, got %v", err)
+	}
+}
+
+func TestPoolBatchSize(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	queue := goethe.NewBoundedFunctionQueue(20)
+
+	pool, err := ethe.NewPool("BatchSizePool", 1, 1, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if err = pool.SetBatchSize(0); err != goethe.ErrIllegalCount {
+		t.Errorf("expected ErrIllegalCount, got %v", err)
+	}
+
+	pool.SetBatchSize(5)
+	if pool.GetBatchSize() != 5 {
+		t.Errorf("expected batch size of five, got %d", pool.GetBatchSize())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+
+	for lcv := 0; lcv < 10; lcv++ {
+		queue.Enqueue(func() {
+			wg.Done()
+		})
+	}
+
+	pool.Start()
+
+	done := make(chan bool)
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("pool did not run every function")
+		return
+	}
+
+	// The statistics are updated after each function returns
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.GetStatistics().CompletedTasks < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if completed := pool.GetStatistics().CompletedTasks; completed != 10 {
+		t.Errorf("expected ten completed tasks, got %d", completed)
+	}
+}
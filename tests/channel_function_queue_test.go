@@ -0,0 +1,259 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/jwells131313/goethe"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChannelFunctionQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	work := make(chan func(), 5)
+	queue := goethe.NewChannelFunctionQueue(work)
+
+	if queue.GetCapacity() != 10 {
+		t.Errorf("expected capacity of ten, got %d", queue.GetCapacity())
+	}
+
+	pool, err := ethe.NewPool("ChannelFunctionQueuePool", 0, 3, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.Start()
+
+	ran := make(chan bool, 10)
+	for lcv := 0; lcv < 5; lcv++ {
+		work <- func() {
+			ran <- true
+		}
+	}
+
+	queue.Enqueue(func(value bool) {
+		ran <- value
+	}, true)
+
+	for lcv := 0; lcv < 6; lcv++ {
+		select {
+		case <-ran:
+		case <-time.After(5 * time.Second):
+			t.Errorf("pool only ran %d functions", lcv)
+			return
+		}
+	}
+
+	if pool.GetStatistics().ThreadsCreated == 0 {
+		t.Error("pool did not grow for functions sent on the channel")
+	}
+}
+
+func TestChannelFunctionQueueSize(t *testing.T) {
+	work := make(chan func(), 2)
+	queue := goethe.NewChannelFunctionQueue(work)
+
+	work <- func() {}
+	work <- func() {}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for queue.GetSize() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if queue.GetSize() != 2 {
+		t.Errorf("expected two functions, got %d", queue.GetSize())
+	}
+
+	for lcv := 0; lcv < 2; lcv++ {
+		if _, err := queue.Dequeue(5 * time.Second); err != nil {
+			t.Errorf("unexpected dequeue error %v", err)
+		}
+	}
+
+	close(work)
+
+	if !queue.IsEmpty() {
+		t.Errorf("expected empty queue, got %d", queue.GetSize())
+	}
+}
+
+func TestChannelFunctionQueueSizeCountsForwardedFunction(t *testing.T) {
+	work := make(chan func(), 1)
+	queue := goethe.NewChannelFunctionQueue(work)
+
+	// One on the queue, one waiting for room on the queue and one on the channel
+	for lcv := 0; lcv < 3; lcv++ {
+		work <- func() {}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(work) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if queue.GetSize() != 3 {
+		t.Errorf("expected three functions, got %d", queue.GetSize())
+	}
+
+	for lcv := 0; lcv < 3; lcv++ {
+		if _, err := queue.Dequeue(5 * time.Second); err != nil {
+			t.Errorf("unexpected dequeue error %v", err)
+			return
+		}
+	}
+
+	close(work)
+
+	if !queue.IsEmpty() {
+		t.Errorf("expected empty queue, got %d", queue.GetSize())
+	}
+}
+
+func TestFunctionQueueChannel(t *testing.T) {
+	queue := goethe.NewBoundedFunctionQueue(10)
+
+	channel, closer := goethe.NewFunctionQueueChannel(queue)
+
+	f := func(string) {}
+	queue.Enqueue(f, "first")
+	queue.Enqueue(f, "second")
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case descriptor := <-channel:
+			if descriptor.Args[0].(string) != expected {
+				t.Errorf("expected %s, got %v", expected, descriptor.Args[0])
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("did not receive %s", expected)
+			return
+		}
+	}
+
+	queue.Enqueue(f, "third")
+
+	// Wait for the third to be taken off the queue and offered on the channel
+	deadline := time.Now().Add(5 * time.Second)
+	for !queue.IsEmpty() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	closer.Close()
+
+	if _, open := <-channel; open {
+		t.Error("channel was not closed")
+	}
+
+	if queue.GetSize() != 1 {
+		t.Errorf("function that was not received was not enqueued again, size %d", queue.GetSize())
+	}
+}
+
+func TestFunctionQueueChannelReportsLostFunction(t *testing.T) {
+	queue := goethe.NewBoundedFunctionQueue(1)
+
+	channel, closer := goethe.NewFunctionQueueChannel(queue)
+
+	f := func(string) {}
+	queue.Enqueue(f, "offered")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !queue.IsEmpty() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// No room is left for the offered function
+	queue.Enqueue(f, "other")
+
+	err := closer.Close()
+
+	requeueErr, ok := err.(*goethe.RequeueError)
+	if !ok {
+		t.Errorf("expected a RequeueError, got %v", err)
+		return
+	}
+
+	if !errors.Is(err, goethe.ErrAtCapacity) {
+		t.Errorf("expected the requeue to fail with ErrAtCapacity, got %v", requeueErr.Err)
+	}
+
+	if requeueErr.Descriptor.Args[0] != "offered" {
+		t.Errorf("expected the offered function, got %v", requeueErr.Descriptor.Args)
+	}
+
+	if _, open := <-channel; open {
+		t.Error("channel was not closed")
+	}
+}
+
+type failingContextQueue struct {
+	goethe.FunctionQueue
+
+	calls int32
+}
+
+func (fq *failingContextQueue) DequeueContext(ctx context.Context) (*goethe.FunctionDescriptor, error) {
+	atomic.AddInt32(&fq.calls, 1)
+
+	return nil, errors.New("queue is broken")
+}
+
+func TestFunctionQueueChannelDoesNotSpinOnErrors(t *testing.T) {
+	queue := &failingContextQueue{
+		FunctionQueue: goethe.NewBoundedFunctionQueue(10),
+	}
+
+	_, closer := goethe.NewFunctionQueueChannel(queue)
+
+	time.Sleep(250 * time.Millisecond)
+	closer.Close()
+
+	if calls := atomic.LoadInt32(&queue.calls); calls > 10 {
+		t.Errorf("dequeue was retried %d times without waiting", calls)
+	}
+}
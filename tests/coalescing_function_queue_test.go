@@ -0,0 +1,132 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalesceKeepFirst(t *testing.T) {
+	queue := goethe.NewCoalescingFunctionQueue(2, goethe.CoalesceKeepFirst)
+
+	f := func(string) {}
+
+	queue.EnqueueKeyed("refresh", f, "first")
+	queue.Enqueue(f, "unkeyed")
+
+	// The queue is full but coalescing does not need room
+	if err := queue.EnqueueKeyed("refresh", f, "second"); err != nil {
+		t.Errorf("unexpected enqueue error %v", err)
+	}
+
+	if err := queue.EnqueueKeyed("other", f, "other"); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if queue.GetCoalescedCount() != 1 {
+		t.Errorf("expected one coalesced function, got %d", queue.GetCoalescedCount())
+	}
+
+	expected := []string{"first", "unkeyed"}
+	if found := dequeueAllNames(t, queue); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	// Once dequeued the key can be queued again
+	queue.EnqueueKeyed("refresh", f, "third")
+	if queue.GetSize() != 1 || queue.GetCoalescedCount() != 1 {
+		t.Errorf("key was not queued again %d/%d", queue.GetSize(), queue.GetCoalescedCount())
+	}
+}
+
+func TestCoalesceKeepLast(t *testing.T) {
+	queue := goethe.NewCoalescingFunctionQueue(10, goethe.CoalesceKeepLast)
+
+	if queue.GetCoalescePolicy() != goethe.CoalesceKeepLast {
+		t.Errorf("unexpected policy %v", queue.GetCoalescePolicy())
+	}
+
+	f := func(string) {}
+
+	queue.EnqueueKeyed("a", f, "a1")
+	queue.EnqueueKeyed("b", f, "b1")
+	queue.EnqueueKeyed("a", f, "a2")
+	queue.EnqueueKeyed("a", f, "a3")
+
+	if queue.GetCoalescedCount() != 2 {
+		t.Errorf("expected two coalesced functions, got %d", queue.GetCoalescedCount())
+	}
+
+	expected := []string{"a3", "b1"}
+	if found := dequeueAllNames(t, queue); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+}
+
+func TestCoalescingFunctionQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	queue := goethe.NewCoalescingFunctionQueue(10, goethe.CoalesceKeepFirst)
+
+	ran := make(chan string, 10)
+	for lcv := 0; lcv < 5; lcv++ {
+		queue.EnqueueKeyed("refresh", func() {
+			ran <- "refresh"
+		})
+	}
+
+	pool, err := ethe.NewPool("CoalescingFunctionQueuePool", 1, 1, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	pool.Start()
+	pool.Shutdown(true)
+	pool.AwaitTermination(5 * time.Second)
+
+	if len(ran) != 1 {
+		t.Errorf("expected the refresh to run once, ran %d times", len(ran))
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestConditionRestoresWriteHoldCount(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+	condition := lock.NewCondition()
+
+	items := make([]int, 0)
+	waiting := make(chan bool)
+	result := make(chan error)
+
+	ethe.Go(func() {
+		lock.WriteLock()
+		lock.WriteLock()
+		defer lock.WriteUnlock()
+		defer lock.WriteUnlock()
+
+		waiting <- true
+
+		for len(items) == 0 {
+			_, err := condition.Await(-1)
+			if err != nil {
+				result <- err
+				return
+			}
+		}
+
+		if lock.GetWriteHoldCount() != 2 {
+			result <- fmt.Errorf("expected write hold count of 2 after Await, got %d", lock.GetWriteHoldCount())
+			return
+		}
+
+		result <- nil
+	})
+
+	<-waiting
+
+	ethe.Go(func() {
+		lock.WriteLock()
+		defer lock.WriteUnlock()
+
+		items = append(items, 1)
+		condition.Signal()
+	})
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("consumer was never signalled")
+	}
+}
+
+func TestConditionAwaitTimesOut(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+	condition := lock.NewCondition()
+
+	type awaitResult struct {
+		signalled bool
+		count     int32
+		err       error
+	}
+
+	result := make(chan awaitResult)
+	ethe.Go(func() {
+		lock.WriteLock()
+		defer lock.WriteUnlock()
+
+		signalled, err := condition.Await(20 * time.Millisecond)
+
+		result <- awaitResult{
+			signalled: signalled,
+			count:     lock.GetWriteHoldCount(),
+			err:       err,
+		}
+	})
+
+	r := <-result
+	if r.err != nil || r.signalled {
+		t.Errorf("expected a timeout, got %v/%v", r.signalled, r.err)
+	}
+	if r.count != 1 {
+		t.Errorf("expected the write lock to be held again, count is %d", r.count)
+	}
+}
+
+func TestConditionSignalAll(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+	condition := lock.NewCondition()
+
+	waiting := make(chan bool)
+	woken := make(chan bool, 3)
+
+	for lcv := 0; lcv < 3; lcv++ {
+		ethe.Go(func() {
+			lock.WriteLock()
+			defer lock.WriteUnlock()
+
+			waiting <- true
+
+			signalled, _ := condition.Await(5 * time.Second)
+			woken <- signalled
+		})
+
+		<-waiting
+	}
+
+	ethe.Go(func() {
+		lock.WriteLock()
+		defer lock.WriteUnlock()
+
+		condition.SignalAll()
+	})
+
+	for lcv := 0; lcv < 3; lcv++ {
+		if !<-woken {
+			t.Error("waiter was not signalled")
+		}
+	}
+}
+
+func TestConditionRequiresWriteLock(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+	condition := lock.NewCondition()
+
+	result := make(chan error, 3)
+	ethe.Go(func() {
+		_, err := condition.Await(0)
+		result <- err
+		result <- condition.Signal()
+
+		lock.ReadLock()
+		defer lock.ReadUnlock()
+
+		result <- condition.SignalAll()
+	})
+
+	for lcv := 0; lcv < 3; lcv++ {
+		if err := <-result; err != goethe.ErrWriteLockNotHeld {
+			t.Errorf("expected ErrWriteLockNotHeld, got %v", err)
+		}
+	}
+}
+
+func TestConditionAwaitDeadlockLeavesLockReleased(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	ethe.EnableDeadlockDetection(true, nil)
+	defer ethe.DisableDeadlockDetection()
+
+	conditionLock := ethe.NewGoetheLock()
+	otherLock := ethe.NewGoetheLock()
+	condition := conditionLock.NewCondition()
+
+	awaiting := make(chan bool)
+	awaitResult := make(chan error, 1)
+	holdCount := make(chan int32, 1)
+	otherResult := make(chan error, 1)
+
+	ethe.Go(func() {
+		otherLock.WriteLock()
+		defer otherLock.WriteUnlock()
+
+		conditionLock.WriteLock()
+
+		awaiting <- true
+
+		// Times out while the other thread holds the condition lock and waits for otherLock
+		_, err := condition.Await(300 * time.Millisecond)
+
+		holdCount <- conditionLock.GetWriteHoldCount()
+		if err == nil {
+			conditionLock.WriteUnlock()
+		}
+
+		awaitResult <- err
+	})
+
+	<-awaiting
+
+	ethe.Go(func() {
+		conditionLock.WriteLock()
+		defer conditionLock.WriteUnlock()
+
+		err := otherLock.WriteLock()
+		if err == nil {
+			otherLock.WriteUnlock()
+		}
+
+		otherResult <- err
+	})
+
+	select {
+	case err := <-awaitResult:
+		if !errors.Is(err, goethe.ErrDeadlock) {
+			t.Errorf("expected a deadlock error from Await, got %v", err)
+			return
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("threads are deadlocked")
+		return
+	}
+
+	if count := <-holdCount; count != 0 {
+		t.Errorf("lock should not be held after a deadlock in Await, hold count is %d", count)
+	}
+
+	select {
+	case err := <-otherResult:
+		if err != nil {
+			t.Errorf("unexpected error in other thread %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("other thread never got the lock")
+	}
+}
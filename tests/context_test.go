@@ -0,0 +1,339 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/jwells131313/goethe"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type contextKey string
+
+func TestReadLockContextCancelled(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	holding := make(chan bool)
+	release := make(chan bool)
+	result := make(chan error)
+
+	ethe.Go(func() {
+		lock.WriteLock()
+		defer lock.WriteUnlock()
+
+		holding <- true
+		<-release
+	})
+
+	<-holding
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ethe.Go(func() {
+		result <- lock.ReadLockContext(ctx)
+	})
+
+	select {
+	case err := <-result:
+		t.Errorf("should not have gotten read lock while write lock held %v", err)
+		return
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("cancelling the context did not wake up the reader")
+	}
+}
+
+func TestLockContextWaitStartsNoGoRoutine(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	holding := make(chan bool)
+	release := make(chan bool)
+	result := make(chan error)
+
+	ethe.Go(func() {
+		lock.WriteLock()
+		defer lock.WriteUnlock()
+
+		holding <- true
+		<-release
+	})
+
+	<-holding
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const waiters = 20
+
+	before := runtime.NumGoroutine()
+	for lcv := 0; lcv < waiters; lcv++ {
+		ethe.Go(func() {
+			result <- lock.ReadLockContext(ctx)
+		})
+	}
+
+	for lcv := 0; len(lock.GetWaitingReaders()) < waiters; lcv++ {
+		if lcv >= 500 {
+			t.Errorf("readers never started waiting, %d waiting", len(lock.GetWaitingReaders()))
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Only the waiting threads themselves, with some room for others
+	if grown := runtime.NumGoroutine() - before; grown >= 2*waiters {
+		t.Errorf("%d go routines were started for %d waiters", grown, waiters)
+	}
+
+	cancel()
+
+	for lcv := 0; lcv < waiters; lcv++ {
+		select {
+		case err := <-result:
+			if err != context.Canceled {
+				t.Errorf("expected context.Canceled, got %v", err)
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("cancelling the context did not wake up the readers")
+			return
+		}
+	}
+}
+
+func TestWriteLockContextDeadline(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	holding := make(chan bool)
+	release := make(chan bool)
+	result := make(chan error)
+
+	ethe.Go(func() {
+		lock.ReadLock()
+		defer lock.ReadUnlock()
+
+		holding <- true
+		<-release
+	})
+
+	<-holding
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	ethe.Go(func() {
+		result <- lock.WriteLockContext(ctx)
+	})
+
+	select {
+	case err := <-result:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+			return
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("deadline did not wake up the writer")
+		return
+	}
+
+	close(release)
+
+	// The lock must still be usable after the writer gave up
+	ethe.Go(func() {
+		result <- lock.WriteLockContext(context.Background())
+		lock.WriteUnlock()
+	})
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("unexpected error getting write lock %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("could not get write lock after reader left")
+	}
+}
+
+func TestDequeueContext(t *testing.T) {
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	cfq, ok := funcQueue.(goethe.ContextFunctionQueue)
+	if !ok {
+		t.Error("bounded function queue should implement ContextFunctionQueue")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := cfq.DequeueContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		return
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		funcQueue.Enqueue(func() {})
+	}()
+
+	descriptor, err := cfq.DequeueContext(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+		return
+	}
+	if descriptor == nil {
+		t.Error("expected a descriptor")
+		return
+	}
+}
+
+func TestGoContext(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+	reply := make(chan interface{})
+
+	_, err := ethe.GoContext(ctx, func(expected string) {
+		found, err := ethe.GetContext()
+		if err != nil {
+			reply <- err
+			return
+		}
+
+		reply <- found.Value(contextKey(expected))
+	}, "key")
+	if err != nil {
+		t.Errorf("could not start thread %v", err)
+		return
+	}
+
+	value := <-reply
+	if value != "value" {
+		t.Errorf("expected value from context, got %v", value)
+		return
+	}
+
+	_, err = ethe.GetContext()
+	if err != goethe.ErrNotGoetheThread {
+		t.Errorf("expected ErrNotGoetheThread, got %v", err)
+		return
+	}
+}
+
+func TestSubmitContextCancelledBeforeRunning(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("SubmitContextPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := false
+	future, err := pool.SubmitContext(ctx, func() {
+		ran = true
+	})
+	if err != nil {
+		t.Errorf("could not submit %v", err)
+		return
+	}
+
+	// pool is not started, so cancel before it can run
+	cancel()
+
+	_, err = future.Get(5 * time.Second)
+	if err != goethe.ErrFutureCancelled {
+		t.Errorf("expected cancelled future, got %v", err)
+		return
+	}
+
+	pool.Start()
+
+	ctx2 := context.WithValue(context.Background(), contextKey("pool"), "job")
+	future, err = pool.SubmitContext(ctx2, func() interface{} {
+		found, _ := ethe.GetContext()
+		return found.Value(contextKey("pool"))
+	})
+	if err != nil {
+		t.Errorf("could not submit %v", err)
+		return
+	}
+
+	results, err := future.Get(5 * time.Second)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+		return
+	}
+	if results[0] != "job" {
+		t.Errorf("expected job from the context, got %v", results[0])
+		return
+	}
+
+	if ran {
+		t.Error("cancelled job should not have run")
+		return
+	}
+}
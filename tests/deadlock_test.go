@@ -0,0 +1,229 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"errors"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestDeadlockDetectedBetweenTwoWriters(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	handled := make(chan *goethe.DeadlockError, 2)
+	ethe.EnableDeadlockDetection(true, func(de *goethe.DeadlockError) {
+		handled <- de
+	})
+	defer ethe.DisableDeadlockDetection()
+
+	lockA := ethe.NewGoetheLock()
+	lockB := ethe.NewGoetheLock()
+
+	holding := make(chan bool)
+	proceed := make(chan bool)
+	results := make(chan error, 2)
+
+	crossLock := func(first, second goethe.Lock) {
+		first.WriteLock()
+		defer first.WriteUnlock()
+
+		holding <- true
+		<-proceed
+
+		err := second.WriteLock()
+		if err == nil {
+			second.WriteUnlock()
+		}
+
+		results <- err
+	}
+
+	ethe.Go(crossLock, lockA, lockB)
+	ethe.Go(crossLock, lockB, lockA)
+
+	<-holding
+	<-holding
+	close(proceed)
+
+	deadlocks := 0
+	for lcv := 0; lcv < 2; lcv++ {
+		select {
+		case err := <-results:
+			if err == nil {
+				continue
+			}
+
+			if !errors.Is(err, goethe.ErrDeadlock) {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+
+			deadlocks++
+		case <-time.After(5 * time.Second):
+			t.Error("threads are deadlocked")
+			return
+		}
+	}
+
+	if deadlocks != 1 {
+		t.Errorf("expected exactly one thread to fail with a deadlock, got %d", deadlocks)
+	}
+
+	select {
+	case de := <-handled:
+		if len(de.Threads) != 2 || len(de.Locks) != 2 {
+			t.Errorf("expected a cycle of two threads and locks, got %v", de)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("deadlock handler was not called")
+	}
+}
+
+func TestDeadlockDetectedReaderBehindWaitingWriter(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	ethe.EnableDeadlockDetection(true, nil)
+	defer ethe.DisableDeadlockDetection()
+
+	lock := ethe.NewGoetheLock()
+
+	readerHolding := make(chan bool)
+	writerWaiting := make(chan bool)
+	results := make(chan error, 1)
+
+	ethe.Go(func() {
+		lock.ReadLock()
+		defer lock.ReadUnlock()
+
+		readerHolding <- true
+		<-writerWaiting
+
+		// The writer is waiting for this thread to release its read lock
+		// and gets preference, so reading again would wait forever
+		err := lock.ReadLock()
+		if err == nil {
+			lock.ReadUnlock()
+		}
+
+		results <- err
+	})
+
+	<-readerHolding
+
+	writerDone := make(chan bool)
+	ethe.Go(func() {
+		lock.WriteLock()
+		lock.WriteUnlock()
+
+		writerDone <- true
+	})
+
+	// Give the writer time to start waiting
+	time.Sleep(100 * time.Millisecond)
+	close(writerWaiting)
+
+	select {
+	case err := <-results:
+		if !errors.Is(err, goethe.ErrDeadlock) {
+			t.Errorf("expected a deadlock error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("reader is deadlocked")
+		return
+	}
+
+	select {
+	case <-writerDone:
+		break
+	case <-time.After(5 * time.Second):
+		t.Error("writer never got the lock")
+	}
+}
+
+func TestNoDeadlockReportedForOrdinaryWait(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	handled := make(chan *goethe.DeadlockError, 1)
+	ethe.EnableDeadlockDetection(true, func(de *goethe.DeadlockError) {
+		handled <- de
+	})
+	defer ethe.DisableDeadlockDetection()
+
+	lock := ethe.NewGoetheLock()
+
+	holding := make(chan bool)
+	release := make(chan bool)
+	ethe.Go(func() {
+		lock.WriteLock()
+		holding <- true
+		<-release
+		lock.WriteUnlock()
+	})
+
+	<-holding
+
+	result := make(chan error)
+	ethe.Go(func() {
+		err := lock.WriteLock()
+		if err == nil {
+			lock.WriteUnlock()
+		}
+
+		result <- err
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	err := <-result
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	select {
+	case de := <-handled:
+		t.Errorf("unexpected deadlock reported %v", de)
+	case <-time.After(100 * time.Millisecond):
+		break
+	}
+}
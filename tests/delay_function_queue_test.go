@@ -0,0 +1,144 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestDelayFunctionQueueOrder(t *testing.T) {
+	queue := goethe.NewDelayFunctionQueue(10)
+
+	f := func(string) {}
+
+	now := time.Now()
+	queue.EnqueueAt(now.Add(60*time.Millisecond), f, "third")
+	queue.EnqueueAt(now.Add(30*time.Millisecond), f, "second")
+	queue.Enqueue(f, "first")
+
+	if queue.GetSize() != 1 || queue.GetDelayedSize() != 2 {
+		t.Errorf("expected one due and two delayed, got %d/%d", queue.GetSize(), queue.GetDelayedSize())
+	}
+
+	if name := dequeueName(t, queue); name != "first" {
+		t.Errorf("expected first, got %s", name)
+	}
+
+	if _, err := queue.Dequeue(0); err != goethe.ErrEmptyQueue {
+		t.Errorf("expected ErrEmptyQueue before anything is due, got %v", err)
+	}
+
+	expected := []string{"second", "third"}
+	for _, name := range expected {
+		descriptor, err := queue.Dequeue(5 * time.Second)
+		if err != nil {
+			t.Errorf("unexpected dequeue error %v", err)
+			return
+		}
+
+		if descriptor.Args[0].(string) != name {
+			t.Errorf("expected %s, got %s", name, descriptor.Args[0])
+		}
+	}
+
+	if elapsed := time.Since(now); elapsed < 60*time.Millisecond {
+		t.Errorf("third was dequeued before it was due, after %v", elapsed)
+	}
+
+	if !queue.IsEmpty() || queue.GetDelayedSize() != 0 {
+		t.Error("queue should be empty")
+	}
+}
+
+func TestDelayFunctionQueueArguments(t *testing.T) {
+	queue := goethe.NewDelayFunctionQueue(1)
+
+	f := func() {}
+
+	if err := queue.EnqueueAfter(-1, f); err != goethe.ErrIllegalDuration {
+		t.Errorf("expected ErrIllegalDuration, got %v", err)
+	}
+
+	if err := queue.EnqueueAfter(time.Hour, f); err != nil {
+		t.Errorf("unexpected enqueue error %v", err)
+	}
+
+	if err := queue.Enqueue(f); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if _, err := queue.Dequeue(10 * time.Millisecond); err != goethe.ErrEmptyQueue {
+		t.Errorf("expected ErrEmptyQueue, got %v", err)
+	}
+}
+
+func TestDelayFunctionQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	queue := goethe.NewDelayFunctionQueue(10)
+
+	ran := make(chan time.Time, 1)
+
+	pool, err := ethe.NewPool("DelayFunctionQueuePool", 0, 2, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.Start()
+
+	enqueued := time.Now()
+	queue.EnqueueAfter(50*time.Millisecond, func() {
+		ran <- time.Now()
+	})
+
+	select {
+	case ranAt := <-ran:
+		if ranAt.Sub(enqueued) < 50*time.Millisecond {
+			t.Errorf("function ran before it was due, after %v", ranAt.Sub(enqueued))
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("pool never ran the delayed function")
+	}
+}
@@ -42,8 +42,10 @@ package tests
 
 import (
 	"errors"
+	"fmt"
 	"github.com/jwells131313/goethe"
 	"testing"
+	"time"
 )
 
 func TestBasicErrorFunctionality(t *testing.T) {
@@ -153,3 +155,131 @@ func TestCapacityWorks(t *testing.T) {
 	}
 
 }
+
+func TestRingErrorQueueOverwritesOldest(t *testing.T) {
+	errorQueue := goethe.NewRingErrorQueue(3)
+
+	if errorQueue.GetCapacity() != 3 {
+		t.Errorf("expected capacity of 3, got %d", errorQueue.GetCapacity())
+		return
+	}
+
+	if _, found := errorQueue.Peek(); found {
+		t.Errorf("should not have found anything in newly created queue")
+		return
+	}
+
+	for lcv := int64(1); lcv <= 5; lcv++ {
+		err := errorQueue.Enqueue(&dummyErrorInformation{
+			tid: lcv,
+			err: errors.New("an error"),
+		})
+		if err != nil {
+			t.Errorf("ring error queue should never reject an error, got %v", err)
+			return
+		}
+	}
+
+	if errorQueue.GetSize() != 3 {
+		t.Errorf("expected size of 3, got %d", errorQueue.GetSize())
+		return
+	}
+
+	if errorQueue.GetDroppedCount() != 2 {
+		t.Errorf("expected two dropped errors, got %d", errorQueue.GetDroppedCount())
+		return
+	}
+
+	info, found := errorQueue.Peek()
+	if !found || info.GetThreadID() != 5 {
+		t.Errorf("expected to peek the most recent error, got %v/%v", info, found)
+		return
+	}
+
+	info, found = errorQueue.Dequeue()
+	if !found || info.GetThreadID() != 3 {
+		t.Errorf("expected to dequeue the oldest remaining error, got %v/%v", info, found)
+		return
+	}
+
+	errorQueue.Enqueue(&dummyErrorInformation{
+		tid: 6,
+		err: errors.New("an error"),
+	})
+
+	// The newest error after the ring has wrapped around
+	info, found = errorQueue.Peek()
+	if !found || info.GetThreadID() != 6 {
+		t.Errorf("expected to peek the most recent error, got %v/%v", info, found)
+		return
+	}
+
+	all := errorQueue.DequeueAll()
+	if len(all) != 3 {
+		t.Errorf("expected three errors, got %d", len(all))
+		return
+	}
+
+	for index, tid := range []int64{4, 5, 6} {
+		if all[index].GetThreadID() != tid {
+			t.Errorf("expected tid %d at index %d, got %d", tid, index, all[index].GetThreadID())
+			return
+		}
+	}
+
+	if !errorQueue.IsEmpty() {
+		t.Errorf("queue should be empty after DequeueAll")
+		return
+	}
+
+	if len(errorQueue.DequeueAll()) != 0 {
+		t.Errorf("DequeueAll of an empty queue should return no errors")
+	}
+}
+
+func TestRingErrorQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	errorQueue := goethe.NewRingErrorQueue(2)
+
+	pool, err := ethe.NewPool("RingErrorQueuePool", 1, 1, 5*time.Minute,
+		goethe.NewBoundedFunctionQueue(10), errorQueue)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	if pool.GetErrorQueue() != errorQueue {
+		t.Errorf("pool did not keep the ring error queue")
+		return
+	}
+
+	for lcv := 0; lcv < 5; lcv++ {
+		pool.GetFunctionQueue().Enqueue(returnsError, lcv)
+	}
+
+	pool.Start()
+	pool.Shutdown(true)
+	pool.AwaitTermination(5 * time.Second)
+
+	if errorQueue.GetDroppedCount() != 3 {
+		t.Errorf("expected three dropped errors, got %d", errorQueue.GetDroppedCount())
+		return
+	}
+
+	all := errorQueue.DequeueAll()
+	if len(all) != 2 {
+		t.Errorf("expected two errors, got %d", len(all))
+		return
+	}
+
+	for index, expected := range []string{"error 3", "error 4"} {
+		if all[index].GetError().Error() != expected {
+			t.Errorf("expected %s at index %d, got %v", expected, index, all[index].GetError())
+		}
+	}
+}
+
+func returnsError(index int) error {
+	return fmt.Errorf("error %d", index)
+}
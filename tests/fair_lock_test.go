@@ -0,0 +1,219 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"sync"
+	"testing"
+	"time"
+)
+
+type acquisitionRecorder struct {
+	mux   sync.Mutex
+	order []string
+}
+
+func (recorder *acquisitionRecorder) record(name string) {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	recorder.order = append(recorder.order, name)
+}
+
+func (recorder *acquisitionRecorder) getOrder() []string {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	return append([]string{}, recorder.order...)
+}
+
+// waitForWaiters waits until the lock has the given number of waiting readers and writers
+func waitForWaiters(lock goethe.Lock, readers, writers int) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(lock.GetWaitingReaders()) == readers && len(lock.GetWaitingWriters()) == writers {
+			return true
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	return false
+}
+
+func holdWriteLock(ethe goethe.ThreadUtilities, lock goethe.Lock) chan bool {
+	holding := make(chan bool)
+	release := make(chan bool)
+
+	ethe.Go(func() {
+		lock.WriteLock()
+		holding <- true
+		<-release
+		lock.WriteUnlock()
+	})
+
+	<-holding
+
+	return release
+}
+
+func TestFairLockGrantsWritersInArrivalOrder(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewFairGoetheLock("fair")
+
+	if !lock.IsFair() || ethe.NewGoetheLock().IsFair() {
+		t.Error("only the lock from NewFairGoetheLock should be fair")
+	}
+
+	release := holdWriteLock(ethe, lock)
+
+	recorder := &acquisitionRecorder{}
+	done := make(chan bool)
+	names := []string{"w1", "w2", "w3", "w4", "w5"}
+
+	for index, name := range names {
+		ethe.Go(func(name string) {
+			lock.WriteLock()
+			recorder.record(name)
+			lock.WriteUnlock()
+
+			done <- true
+		}, name)
+
+		if !waitForWaiters(lock, 0, index+1) {
+			t.Errorf("writer %s never started waiting", name)
+			return
+		}
+	}
+
+	close(release)
+	for range names {
+		<-done
+	}
+
+	order := recorder.getOrder()
+	for index, name := range names {
+		if order[index] != name {
+			t.Errorf("expected writers in order %v, got %v", names, order)
+			return
+		}
+	}
+
+	stats := lock.GetWaitStatistics()
+	if len(stats) != len(names) {
+		t.Errorf("expected wait statistics for %d threads, got %d", len(names), len(stats))
+	}
+	for tid, stat := range stats {
+		if stat.Waits != 1 || stat.TotalWait <= 0 || stat.MaxWait != stat.TotalWait {
+			t.Errorf("unexpected wait statistics for thread %d: %+v", tid, stat)
+		}
+	}
+
+	lock.ResetWaitStatistics()
+	if len(lock.GetWaitStatistics()) != 0 {
+		t.Error("wait statistics were not reset")
+	}
+}
+
+func TestFairLockBatchesReaders(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewFairGoetheLock("")
+
+	release := holdWriteLock(ethe, lock)
+
+	recorder := &acquisitionRecorder{}
+	done := make(chan bool)
+
+	var readersIn sync.WaitGroup
+	readersIn.Add(2)
+
+	reader := func(name string, batched bool) {
+		lock.ReadLock()
+		recorder.record(name)
+
+		if batched {
+			// Both of the first readers must be in at the same time
+			readersIn.Done()
+			readersIn.Wait()
+		}
+
+		lock.ReadUnlock()
+
+		done <- true
+	}
+
+	writer := func(name string) {
+		lock.WriteLock()
+		recorder.record(name)
+		lock.WriteUnlock()
+
+		done <- true
+	}
+
+	ethe.Go(reader, "r1", true)
+	waitForWaiters(lock, 1, 0)
+	ethe.Go(reader, "r2", true)
+	waitForWaiters(lock, 2, 0)
+	ethe.Go(writer, "w1")
+	waitForWaiters(lock, 2, 1)
+	ethe.Go(reader, "r3", false)
+	if !waitForWaiters(lock, 3, 1) {
+		t.Error("threads never started waiting")
+		return
+	}
+
+	close(release)
+	for lcv := 0; lcv < 4; lcv++ {
+		select {
+		case <-done:
+			break
+		case <-time.After(5 * time.Second):
+			t.Errorf("threads did not finish, order so far %v", recorder.getOrder())
+			return
+		}
+	}
+
+	order := recorder.getOrder()
+	if order[2] != "w1" || order[3] != "r3" {
+		t.Errorf("expected r1 and r2 then w1 then r3, got %v", order)
+	}
+}
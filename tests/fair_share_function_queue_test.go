@@ -0,0 +1,145 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"reflect"
+	"testing"
+)
+
+func dequeueAllNames(t *testing.T, queue goethe.FunctionQueue) []string {
+	retVal := make([]string, 0)
+	for !queue.IsEmpty() {
+		retVal = append(retVal, dequeueName(t, queue))
+	}
+
+	return retVal
+}
+
+func TestFairShareRoundRobin(t *testing.T) {
+	queue := goethe.NewFairShareFunctionQueue(100, 0)
+
+	f := func(string) {}
+
+	// The flooding tenant gets there first
+	for lcv := 0; lcv < 4; lcv++ {
+		queue.EnqueueForTenant("flood", f, "flood")
+	}
+	queue.EnqueueForTenant("quiet", f, "quiet")
+	queue.Enqueue(f, "default")
+
+	if queue.GetTenantSize("flood") != 4 || queue.GetTenantSize(goethe.DefaultTenant) != 1 {
+		t.Errorf("unexpected tenant sizes %d/%d", queue.GetTenantSize("flood"), queue.GetTenantSize(goethe.DefaultTenant))
+	}
+
+	expectedTenants := []string{goethe.DefaultTenant, "flood", "quiet"}
+	if tenants := queue.GetTenants(); !reflect.DeepEqual(tenants, expectedTenants) {
+		t.Errorf("expected tenants %v, got %v", expectedTenants, tenants)
+	}
+
+	expected := []string{"flood", "quiet", "default", "flood", "flood", "flood"}
+	if found := dequeueAllNames(t, queue); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	if tenants := queue.GetTenants(); len(tenants) != 0 {
+		t.Errorf("expected no tenants once empty, got %v", tenants)
+	}
+}
+
+func TestFairShareWeights(t *testing.T) {
+	queue := goethe.NewFairShareFunctionQueue(100, 0)
+
+	if err := queue.SetTenant("heavy", 0, 0); err != goethe.ErrIllegalCount {
+		t.Errorf("expected ErrIllegalCount, got %v", err)
+	}
+
+	queue.SetTenant("heavy", 2, 0)
+
+	f := func(string) {}
+
+	for lcv := 0; lcv < 4; lcv++ {
+		queue.EnqueueForTenant("heavy", f, "heavy")
+		queue.EnqueueForTenant("light", f, "light")
+	}
+
+	expected := []string{"heavy", "heavy", "light", "heavy", "heavy", "light", "light", "light"}
+	if found := dequeueAllNames(t, queue); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	if tenants := queue.GetTenants(); !reflect.DeepEqual(tenants, []string{"heavy"}) {
+		t.Errorf("expected only the configured tenant, got %v", tenants)
+	}
+}
+
+func TestFairShareCapacity(t *testing.T) {
+	queue := goethe.NewFairShareFunctionQueue(3, 2)
+
+	f := func() {}
+
+	queue.EnqueueForTenant("a", f)
+	queue.EnqueueForTenant("a", f)
+
+	if err := queue.EnqueueForTenant("a", f); err != goethe.ErrAtCapacity {
+		t.Errorf("expected tenant to be at capacity, got %v", err)
+	}
+
+	if err := queue.EnqueueForTenant("b", f); err != nil {
+		t.Errorf("unexpected enqueue error %v", err)
+	}
+
+	if err := queue.EnqueueForTenant("c", f); err != goethe.ErrAtCapacity {
+		t.Errorf("expected queue to be at capacity, got %v", err)
+	}
+
+	queue.SetTenant("a", 1, 0)
+	queue.Dequeue(0)
+
+	if err := queue.EnqueueForTenant("a", f); err != nil {
+		t.Errorf("unexpected enqueue error with unlimited tenant %v", err)
+	}
+
+	if queue.GetSize() != 3 || queue.GetTenantSize("a") != 2 {
+		t.Errorf("unexpected sizes %d/%d", queue.GetSize(), queue.GetTenantSize("a"))
+	}
+}
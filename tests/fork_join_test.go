@@ -0,0 +1,212 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"errors"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestForkJoinRecursiveSum(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	pool, err := ethe.NewForkJoinPool(4, nil)
+	if err != nil {
+		t.Errorf("could not create fork join pool %v", err)
+		return
+	}
+	defer pool.Shutdown()
+
+	var sum func(low, high int) int
+	sum = func(low, high int) int {
+		if high-low <= 1000 {
+			retVal := 0
+			for lcv := low; lcv < high; lcv++ {
+				retVal += lcv
+			}
+
+			// Give the idle threads a chance to steal
+			time.Sleep(100 * time.Microsecond)
+
+			return retVal
+		}
+
+		middle := (low + high) / 2
+
+		left, err := pool.Fork(sum, low, middle)
+		if err != nil {
+			t.Errorf("could not fork %v", err)
+			return 0
+		}
+
+		right := sum(middle, high)
+
+		leftResults, err := left.Join()
+		if err != nil {
+			t.Errorf("join failed %v", err)
+			return 0
+		}
+
+		return leftResults[0].(int) + right
+	}
+
+	results, err := pool.Invoke(sum, 0, 100000)
+	if err != nil {
+		t.Errorf("invoke failed %v", err)
+		return
+	}
+
+	if results[0].(int) != 4999950000 {
+		t.Errorf("unexpected sum %d", results[0])
+	}
+
+	if pool.GetStealCount() == 0 {
+		t.Error("expected idle threads to steal some work")
+	}
+
+	if pool.GetQueuedTaskCount() != 0 {
+		t.Errorf("expected no queued tasks, got %d", pool.GetQueuedTaskCount())
+	}
+}
+
+func TestForkJoinErrorsAndShutdown(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	errorQueue := goethe.NewBoundedErrorQueue(10)
+
+	pool, err := ethe.NewForkJoinPool(2, errorQueue)
+	if err != nil {
+		t.Errorf("could not create fork join pool %v", err)
+		return
+	}
+
+	expected := errors.New("expected")
+	task, err := pool.Fork(func() error {
+		return expected
+	})
+	if err != nil {
+		t.Errorf("could not fork %v", err)
+		return
+	}
+
+	if _, err = task.Join(); err != expected {
+		t.Errorf("expected error from Join, got %v", err)
+	}
+
+	info, found := errorQueue.Dequeue()
+	if !found || info.GetError() != expected {
+		t.Errorf("expected error on error queue, got %v/%v", info, found)
+	}
+
+	_, err = pool.Invoke(func() error {
+		_, err := pool.AwaitTermination(0)
+		return err
+	})
+	if err != goethe.ErrNotCalledOnCorrectThread {
+		t.Errorf("expected ErrNotCalledOnCorrectThread, got %v", err)
+	}
+
+	pool.Shutdown()
+
+	if _, err = pool.Fork(func() {}); err != goethe.ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+
+	terminated, err := pool.AwaitTermination(5 * time.Second)
+	if err != nil || !terminated {
+		t.Errorf("pool did not terminate %v/%v", terminated, err)
+	}
+
+	if _, err = ethe.NewForkJoinPool(0, nil); err == nil {
+		t.Error("expected error creating pool with no threads")
+	}
+}
+
+func TestForkJoinWorkerForksAfterShutdown(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	pool, err := ethe.NewForkJoinPool(2, nil)
+	if err != nil {
+		t.Errorf("could not create fork join pool %v", err)
+		return
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	task, err := pool.Fork(func() (int, error) {
+		started <- true
+		<-release
+
+		child, err := pool.Fork(func() int {
+			return 7
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		results, err := child.Join()
+		if err != nil {
+			return 0, err
+		}
+
+		return results[0].(int), nil
+	})
+	if err != nil {
+		t.Errorf("could not fork %v", err)
+		return
+	}
+
+	<-started
+	pool.Shutdown()
+	close(release)
+
+	results, err := task.Join()
+	if err != nil || results[0].(int) != 7 {
+		t.Errorf("expected the fork from the worker to run, got %v/%v", results, err)
+	}
+
+	terminated, err := pool.AwaitTermination(5 * time.Second)
+	if err != nil || !terminated {
+		t.Errorf("pool did not terminate %v/%v", terminated, err)
+	}
+}
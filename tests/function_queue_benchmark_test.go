@@ -0,0 +1,164 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const benchmarkCapacity = 4096
+
+var functionQueueFactories = []struct {
+	name    string
+	factory func(uint32) goethe.FunctionQueue
+}{
+	{"Bounded", func(capacity uint32) goethe.FunctionQueue {
+		return goethe.NewBoundedFunctionQueue(capacity)
+	}},
+	{"Ring", func(capacity uint32) goethe.FunctionQueue {
+		return goethe.NewRingFunctionQueue(capacity)
+	}},
+}
+
+func noop() {}
+
+// BenchmarkFunctionQueueEnqueueDequeue measures a single thread enqueueing
+// and dequeueing one function at a time
+func BenchmarkFunctionQueueEnqueueDequeue(b *testing.B) {
+	for _, fq := range functionQueueFactories {
+		b.Run(fq.name, func(b *testing.B) {
+			queue := fq.factory(benchmarkCapacity)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for lcv := 0; lcv < b.N; lcv++ {
+				queue.Enqueue(noop)
+				queue.Dequeue(0)
+			}
+		})
+	}
+}
+
+// BenchmarkFunctionQueueWithCallback is the same as the above with a state
+// change callback set, as a pool does
+func BenchmarkFunctionQueueWithCallback(b *testing.B) {
+	for _, fq := range functionQueueFactories {
+		b.Run(fq.name, func(b *testing.B) {
+			queue := fq.factory(benchmarkCapacity)
+			queue.SetStateChangeCallback(func(goethe.FunctionQueue) {})
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for lcv := 0; lcv < b.N; lcv++ {
+				queue.Enqueue(noop)
+				queue.Dequeue(0)
+			}
+		})
+	}
+}
+
+// BenchmarkFunctionQueueParallel has every benchmark goroutine both
+// producing and consuming functions
+func BenchmarkFunctionQueueParallel(b *testing.B) {
+	for _, fq := range functionQueueFactories {
+		b.Run(fq.name, func(b *testing.B) {
+			queue := fq.factory(benchmarkCapacity)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if queue.Enqueue(noop) == nil {
+						queue.Dequeue(0)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkFunctionQueuePool runs tiny tasks through a pool
+func BenchmarkFunctionQueuePool(b *testing.B) {
+	ethe := goethe.GetGoethe()
+
+	for _, fq := range functionQueueFactories {
+		b.Run(fq.name, func(b *testing.B) {
+			queue := fq.factory(benchmarkCapacity)
+
+			pool, err := ethe.NewPool("BenchmarkPool"+fq.name, 4, 4, 5*time.Minute, queue, nil)
+			if err != nil {
+				b.Errorf("could not create pool %v", err)
+				return
+			}
+
+			var ran int64
+			task := func() {
+				atomic.AddInt64(&ran, 1)
+			}
+
+			pool.Start()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for lcv := 0; lcv < b.N; lcv++ {
+				for queue.Enqueue(task) == goethe.ErrAtCapacity {
+					time.Sleep(time.Microsecond)
+				}
+			}
+
+			pool.Shutdown(true)
+			pool.AwaitTermination(time.Minute)
+
+			b.StopTimer()
+
+			if atomic.LoadInt64(&ran) != int64(b.N) {
+				b.Errorf("expected %d tasks to run, ran %d", b.N, ran)
+			}
+		})
+	}
+}
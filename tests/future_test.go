@@ -0,0 +1,193 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"errors"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestFutureGetsAllReturnValues(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	future, err := ethe.GoWithFuture(func(a, b int) (int, string, error) {
+		return a + b, "hello", nil
+	}, 1, 2)
+	if err != nil {
+		t.Errorf("could not start future %v", err)
+		return
+	}
+
+	results, err := future.Get(-1)
+	if err != nil {
+		t.Errorf("unexpected error from future %v", err)
+		return
+	}
+
+	if len(results) != 3 {
+		t.Errorf("expected three results, got %d", len(results))
+		return
+	}
+
+	if results[0].(int) != 3 {
+		t.Errorf("expected 3 as first result, got %v", results[0])
+		return
+	}
+	if results[1].(string) != "hello" {
+		t.Errorf("expected hello as second result, got %v", results[1])
+		return
+	}
+	if results[2] != nil {
+		t.Errorf("expected nil as third result, got %v", results[2])
+		return
+	}
+
+	if !future.IsDone() {
+		t.Error("future should be done after Get returned")
+		return
+	}
+
+	if future.GetThreadID() < 10 {
+		t.Errorf("unexpected thread id %d", future.GetThreadID())
+		return
+	}
+
+	if future.Cancel() {
+		t.Error("should not be able to cancel a completed future")
+		return
+	}
+}
+
+func TestFutureReturnsFirstError(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	expected := errors.New("first error")
+
+	future, err := ethe.GoWithFuture(func() (error, int, error) {
+		return expected, 13, errors.New("second error")
+	})
+	if err != nil {
+		t.Errorf("could not start future %v", err)
+		return
+	}
+
+	results, err := future.Get(-1)
+	if err != expected {
+		t.Errorf("expected first error but got %v", err)
+		return
+	}
+
+	if len(results) != 3 || results[1].(int) != 13 {
+		t.Errorf("unexpected results %v", results)
+		return
+	}
+
+	if future.GetError() != expected {
+		t.Errorf("expected first error from GetError but got %v", future.GetError())
+		return
+	}
+}
+
+func TestFutureGetTimesOut(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	release := make(chan bool)
+
+	future, err := ethe.GoWithFuture(func() int {
+		<-release
+		return 1
+	})
+	if err != nil {
+		t.Errorf("could not start future %v", err)
+		return
+	}
+
+	_, err = future.Get(0)
+	if err != goethe.ErrFutureNotDone {
+		t.Errorf("expected not done immediately, got %v", err)
+		return
+	}
+
+	before := time.Now()
+
+	_, err = future.Get(500 * time.Millisecond)
+	if err != goethe.ErrFutureNotDone {
+		t.Errorf("expected not done after waiting, got %v", err)
+		return
+	}
+
+	elapsed := time.Since(before)
+	if elapsed < 500*time.Millisecond {
+		t.Errorf("should have waited at least half a second, waited %v", elapsed)
+		return
+	}
+
+	_, err = future.Get(-2)
+	if err != goethe.ErrIllegalDuration {
+		t.Errorf("expected illegal duration error, got %v", err)
+		return
+	}
+
+	close(release)
+
+	results, err := future.Get(5 * time.Second)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+		return
+	}
+
+	if results[0].(int) != 1 {
+		t.Errorf("unexpected result %v", results[0])
+		return
+	}
+}
+
+func TestFutureBadArguments(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	_, err := ethe.GoWithFuture(func(a int) {}, "not an int")
+	if err == nil {
+		t.Error("should have failed with mismatched argument type")
+		return
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestLockOwnershipIntrospection(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	lock := ethe.NewNamedGoetheLock("introspected")
+	if lock.GetName() != "introspected" {
+		t.Errorf("unexpected lock name %s", lock.GetName())
+	}
+
+	if lock.GetWriteOwner() != -1 {
+		t.Errorf("unlocked lock has write owner %d", lock.GetWriteOwner())
+	}
+
+	type holdCounts struct {
+		tid           int64
+		write, read   int32
+		owner         int64
+		readerHolders map[int64]int32
+	}
+
+	result := make(chan holdCounts)
+	release := make(chan bool)
+	ethe.Go(func() {
+		lock.WriteLock()
+		lock.WriteLock()
+		lock.ReadLock()
+
+		result <- holdCounts{
+			tid:           ethe.GetThreadID(),
+			write:         lock.GetWriteHoldCount(),
+			read:          lock.GetReadHoldCount(),
+			owner:         lock.GetWriteOwner(),
+			readerHolders: lock.GetReadHolders(),
+		}
+
+		<-release
+
+		lock.ReadUnlock()
+		lock.WriteUnlock()
+		lock.WriteUnlock()
+
+		result <- holdCounts{}
+	})
+
+	counts := <-result
+	if counts.write != 2 || counts.read != 1 {
+		t.Errorf("expected write count 2 and read count 1, got %d/%d", counts.write, counts.read)
+	}
+	if counts.owner != counts.tid || lock.GetWriteOwner() != counts.tid {
+		t.Errorf("expected owner %d, got %d", counts.tid, counts.owner)
+	}
+	if len(counts.readerHolders) != 1 || counts.readerHolders[counts.tid] != 1 {
+		t.Errorf("unexpected read holders %v", counts.readerHolders)
+	}
+
+	if lock.GetWriteHoldCount() != 0 {
+		t.Error("non-goethe thread should not have a write hold count")
+	}
+
+	close(release)
+	<-result
+
+	if lock.GetWriteOwner() != -1 || len(lock.GetReadHolders()) != 0 {
+		t.Errorf("lock still held after release %d/%v", lock.GetWriteOwner(), lock.GetReadHolders())
+	}
+}
+
+func TestLockWaiterIntrospection(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	lock := ethe.NewGoetheLock()
+
+	holding := make(chan bool)
+	release := make(chan bool)
+	ethe.Go(func() {
+		lock.WriteLock()
+		holding <- true
+		<-release
+		lock.WriteUnlock()
+	})
+
+	<-holding
+
+	done := make(chan bool, 2)
+	writerTid, _ := ethe.Go(func() {
+		lock.WriteLock()
+		lock.WriteUnlock()
+		done <- true
+	})
+	readerTid, _ := ethe.Go(func() {
+		lock.ReadLock()
+		lock.ReadUnlock()
+		done <- true
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(lock.GetWaitingWriters()) == 1 && len(lock.GetWaitingReaders()) == 1 {
+			break
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	writers := lock.GetWaitingWriters()
+	readers := lock.GetWaitingReaders()
+	if len(writers) != 1 || writers[0] != writerTid {
+		t.Errorf("expected waiting writer %d, got %v", writerTid, writers)
+	}
+	if len(readers) != 1 || readers[0] != readerTid {
+		t.Errorf("expected waiting reader %d, got %v", readerTid, readers)
+	}
+
+	close(release)
+	<-done
+	<-done
+
+	if len(lock.GetWaitingWriters()) != 0 || len(lock.GetWaitingReaders()) != 0 {
+		t.Errorf("waiters remain after release %v/%v", lock.GetWaitingWriters(), lock.GetWaitingReaders())
+	}
+}
@@ -0,0 +1,263 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestDowngradeToRead(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	downgraded := make(chan bool)
+	release := make(chan bool)
+	errs := make(chan error, 2)
+
+	ethe.Go(func() {
+		lock.WriteLock()
+
+		err := lock.DowngradeToRead()
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		if lock.GetReadHoldCount() != 1 || lock.GetWriteHoldCount() != 0 {
+			errs <- fmt.Errorf("unexpected hold counts %d/%d", lock.GetReadHoldCount(), lock.GetWriteHoldCount())
+			return
+		}
+
+		downgraded <- true
+		<-release
+
+		errs <- lock.ReadUnlock()
+	})
+
+	<-downgraded
+
+	// Other readers can get in, writers can not
+	readerResult := make(chan bool)
+	ethe.Go(func() {
+		gotIt, _ := lock.TryReadLock(0)
+		if gotIt {
+			lock.ReadUnlock()
+		}
+		readerResult <- gotIt
+	})
+	if !<-readerResult {
+		t.Error("reader could not get the lock after a downgrade")
+	}
+
+	writerResult := make(chan bool)
+	ethe.Go(func() {
+		gotIt, _ := lock.TryWriteLock(0)
+		if gotIt {
+			lock.WriteUnlock()
+		}
+		writerResult <- gotIt
+	})
+	if <-writerResult {
+		t.Error("writer got the lock while the downgraded read lock was held")
+	}
+
+	close(release)
+	if err := <-errs; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if lock.IsLocked() {
+		t.Error("lock still held after the read lock was released")
+	}
+}
+
+func TestDowngradeWithoutWriteLock(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	result := make(chan error)
+	ethe.Go(func() {
+		result <- lock.DowngradeToRead()
+	})
+
+	if err := <-result; err != goethe.ErrWriteLockNotHeld {
+		t.Errorf("expected ErrWriteLockNotHeld, got %v", err)
+	}
+}
+
+func TestUpgradeWaitsForOtherReaders(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	otherHolding := make(chan bool)
+	otherRelease := make(chan bool)
+	ethe.Go(func() {
+		lock.ReadLock()
+		otherHolding <- true
+		<-otherRelease
+		lock.ReadUnlock()
+	})
+
+	<-otherHolding
+
+	type upgradeResult struct {
+		immediate, eventual bool
+		err                 error
+	}
+
+	upgraded := make(chan upgradeResult)
+	ethe.Go(func() {
+		lock.ReadLock()
+		defer lock.ReadUnlock()
+
+		immediate, err := lock.TryUpgrade(0)
+		if err != nil {
+			upgraded <- upgradeResult{err: err}
+			return
+		}
+
+		eventual, err := lock.TryUpgrade(5 * time.Second)
+		if eventual {
+			if lock.GetWriteOwner() != ethe.GetThreadID() || lock.GetReadHoldCount() != 1 {
+				err = fmt.Errorf("unexpected state after upgrade %d/%d", lock.GetWriteOwner(), lock.GetReadHoldCount())
+			}
+
+			lock.WriteUnlock()
+		}
+
+		upgraded <- upgradeResult{
+			immediate: immediate,
+			eventual:  eventual,
+			err:       err,
+		}
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	close(otherRelease)
+
+	result := <-upgraded
+	if result.err != nil {
+		t.Errorf("unexpected error %v", result.err)
+		return
+	}
+
+	if result.immediate {
+		t.Error("upgrade should not succeed while another thread holds the read lock")
+	}
+	if !result.eventual {
+		t.Error("upgrade should succeed once the other reader leaves")
+	}
+
+	if lock.IsLocked() {
+		t.Error("lock still held after upgrade was released")
+	}
+}
+
+func TestOnlyOneUpgrader(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	bothHolding := make(chan bool)
+	proceed := make(chan bool)
+	results := make(chan error, 2)
+
+	upgrader := func() {
+		lock.ReadLock()
+		bothHolding <- true
+		<-proceed
+
+		gotIt, err := lock.TryUpgrade(5 * time.Second)
+		if err != nil {
+			lock.ReadUnlock()
+			results <- err
+			return
+		}
+
+		if gotIt {
+			lock.WriteUnlock()
+		}
+		lock.ReadUnlock()
+
+		results <- nil
+	}
+
+	ethe.Go(upgrader)
+	ethe.Go(upgrader)
+
+	<-bothHolding
+	<-bothHolding
+	close(proceed)
+
+	var failures []error
+	for lcv := 0; lcv < 2; lcv++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				failures = append(failures, err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("upgraders are deadlocked")
+			return
+		}
+	}
+
+	if len(failures) != 1 || failures[0] != goethe.ErrUpgradeInProgress {
+		t.Errorf("expected one ErrUpgradeInProgress, got %v", failures)
+	}
+}
+
+func TestUpgradeWithoutReadLock(t *testing.T) {
+	ethe := goethe.GetGoethe()
+	lock := ethe.NewGoetheLock()
+
+	result := make(chan error)
+	ethe.Go(func() {
+		_, err := lock.TryUpgrade(0)
+		result <- err
+	})
+
+	if err := <-result; err != goethe.ErrReadLockNotHeld {
+		t.Errorf("expected ErrReadLockNotHeld, got %v", err)
+	}
+}
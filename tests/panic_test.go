@@ -0,0 +1,179 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolRecoversFromPanic(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+	errorQueue := goethe.NewBoundedErrorQueue(10)
+
+	pool, err := ethe.NewPool("PanicPool", 1, 1, 1*time.Minute, funcQueue, errorQueue)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.SetPanicPolicy(goethe.PanicRecover)
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	funcQueue.Enqueue(func() {
+		panic("pool panic")
+	})
+
+	ran := make(chan bool)
+	funcQueue.Enqueue(func() {
+		ran <- true
+	})
+
+	select {
+	case <-ran:
+		break
+	case <-time.After(5 * time.Second):
+		t.Error("pool did not run the job after the panic")
+		return
+	}
+
+	info, found := errorQueue.Dequeue()
+	if !found {
+		t.Error("panic was not placed on the error queue")
+		return
+	}
+
+	panicErr, ok := info.GetError().(goethe.PanicError)
+	if !ok {
+		t.Errorf("expected a PanicError, got %v", info.GetError())
+		return
+	}
+
+	if panicErr.GetPanicValue() != "pool panic" {
+		t.Errorf("unexpected panic value %v", panicErr.GetPanicValue())
+	}
+
+	if !strings.Contains(string(panicErr.GetStack()), "panic_test.go") {
+		t.Errorf("stack does not contain the panicking function:\n%s", panicErr.GetStack())
+	}
+
+	if pool.GetCurrentThreadCount() != 1 {
+		t.Errorf("expected one thread in the pool, got %d", pool.GetCurrentThreadCount())
+	}
+}
+
+func TestFutureGetsPanicError(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	oldPolicy := ethe.GetPanicPolicy()
+	ethe.SetPanicPolicy(goethe.PanicRecover)
+	defer ethe.SetPanicPolicy(oldPolicy)
+
+	future, err := ethe.GoWithFuture(func() int {
+		panic("future panic")
+	})
+	if err != nil {
+		t.Errorf("could not start future %v", err)
+		return
+	}
+
+	results, err := future.Get(5 * time.Second)
+	if err == nil {
+		t.Errorf("expected an error from a panicking future, got %v", results)
+		return
+	}
+
+	panicErr, ok := err.(goethe.PanicError)
+	if !ok {
+		t.Errorf("expected a PanicError, got %v", err)
+		return
+	}
+
+	if panicErr.GetPanicValue() != "future panic" {
+		t.Errorf("unexpected panic value %v", panicErr.GetPanicValue())
+	}
+}
+
+func TestPanicHandlerCalled(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	oldPolicy := ethe.GetPanicPolicy()
+	ethe.SetPanicPolicy(goethe.PanicRecover)
+	defer ethe.SetPanicPolicy(oldPolicy)
+
+	handled := make(chan goethe.ErrorInformation, 1)
+	ethe.SetPanicHandler(func(info goethe.ErrorInformation) {
+		handled <- info
+	})
+	defer ethe.SetPanicHandler(nil)
+
+	tid, err := ethe.Go(func() {
+		panic("handler panic")
+	})
+	if err != nil {
+		t.Errorf("could not start thread %v", err)
+		return
+	}
+
+	select {
+	case info := <-handled:
+		if info.GetThreadID() != tid {
+			t.Errorf("expected thread id %d, got %d", tid, info.GetThreadID())
+		}
+
+		panicErr, ok := info.GetError().(goethe.PanicError)
+		if !ok || panicErr.GetPanicValue() != "handler panic" {
+			t.Errorf("unexpected error given to the handler %v", info.GetError())
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("panic handler was not called")
+	}
+}
@@ -0,0 +1,317 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"github.com/jwells131313/goethe"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type persistentJob struct {
+	Name  string
+	Count int
+}
+
+func TestPersistentFunctionQueueReplaysUnfinished(t *testing.T) {
+	directory, err := ioutil.TempDir("", "goethe-persistent")
+	if err != nil {
+		t.Errorf("could not create directory %v", err)
+		return
+	}
+	defer os.RemoveAll(directory)
+
+	ran := make([]persistentJob, 0)
+	handler := func(job persistentJob, extra int) {
+		job.Count += extra
+		ran = append(ran, job)
+	}
+
+	queue, err := goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not create queue %v", err)
+		return
+	}
+
+	queue.RegisterHandler("job", handler)
+
+	queue.EnqueueTask("job", persistentJob{Name: "first", Count: 1}, 1)
+	queue.Enqueue("job", persistentJob{Name: "second", Count: 2}, 2)
+	queue.EnqueueTask("job", persistentJob{Name: "third", Count: 3}, 3)
+
+	if queue.GetSize() != 3 {
+		t.Errorf("expected three tasks, got %d", queue.GetSize())
+	}
+
+	// Run the first task to completion and take the second but never run it
+	descriptor, _ := queue.Dequeue(0)
+	descriptor.UserCall.(func(persistentJob, int))(descriptor.Args[0].(persistentJob), descriptor.Args[1].(int))
+	queue.Dequeue(0)
+
+	queue.Close()
+
+	if err = queue.EnqueueTask("job", persistentJob{}, 0); err != goethe.ErrQueueClosed {
+		t.Errorf("expected ErrQueueClosed, got %v", err)
+	}
+
+	queue, err = goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not reopen queue %v", err)
+		return
+	}
+	defer queue.Close()
+
+	if _, err = queue.Replay(); err != goethe.ErrUnknownHandler {
+		t.Errorf("expected ErrUnknownHandler before registering, got %v", err)
+	}
+
+	queue.RegisterHandler("job", handler)
+
+	replayed, err := queue.Replay()
+	if err != nil || replayed != 2 {
+		t.Errorf("expected two replayed tasks, got %d/%v", replayed, err)
+		return
+	}
+
+	for !queue.IsEmpty() {
+		descriptor, _ := queue.Dequeue(0)
+		descriptor.UserCall.(func(persistentJob, int))(descriptor.Args[0].(persistentJob), descriptor.Args[1].(int))
+	}
+
+	expected := []persistentJob{{"first", 2}, {"second", 4}, {"third", 6}}
+	if len(ran) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, ran)
+		return
+	}
+
+	for index, job := range expected {
+		if ran[index] != job {
+			t.Errorf("expected %v at %d, got %v", job, index, ran[index])
+		}
+	}
+}
+
+func TestPersistentFunctionQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	directory, err := ioutil.TempDir("", "goethe-persistent")
+	if err != nil {
+		t.Errorf("could not create directory %v", err)
+		return
+	}
+	defer os.RemoveAll(directory)
+
+	queue, err := goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not create queue %v", err)
+		return
+	}
+
+	done := make(chan string, 2)
+	queue.RegisterHandler("echo", func(message string) {
+		done <- message
+	})
+
+	pool, err := ethe.NewPool("PersistentFunctionQueuePool", 1, 1, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	pool.Start()
+
+	queue.EnqueueTask("echo", "hello")
+	queue.EnqueueTask("echo", "world")
+
+	for _, expected := range []string{"hello", "world"} {
+		select {
+		case message := <-done:
+			if message != expected {
+				t.Errorf("expected %s, got %s", expected, message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("pool did not run task %s", expected)
+			return
+		}
+	}
+
+	pool.Close()
+	pool.AwaitTermination(5 * time.Second)
+	queue.Close()
+
+	// A partly written record at the end of the log is ignored
+	logFile, _ := os.OpenFile(filepath.Join(directory, "goethe-queue.log"), os.O_APPEND|os.O_WRONLY, 0600)
+	logFile.WriteString(`{"op":"enq`)
+	logFile.Close()
+
+	queue, err = goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not reopen queue %v", err)
+		return
+	}
+	defer queue.Close()
+
+	replayed, err := queue.Replay()
+	if err != nil || replayed != 0 {
+		t.Errorf("expected nothing to replay, got %d/%v", replayed, err)
+	}
+
+	if err = queue.EnqueueTask("echo", "hello"); err != goethe.ErrUnknownHandler {
+		t.Errorf("expected ErrUnknownHandler, got %v", err)
+	}
+
+	if err = queue.Enqueue(func() {}); err == nil {
+		t.Error("expected an error enqueueing a function")
+	}
+}
+
+func TestPersistentFunctionQueuePanicNotAcknowledged(t *testing.T) {
+	directory, err := ioutil.TempDir("", "goethe-persistent")
+	if err != nil {
+		t.Errorf("could not create directory %v", err)
+		return
+	}
+	defer os.RemoveAll(directory)
+
+	queue, err := goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not create queue %v", err)
+		return
+	}
+
+	queue.RegisterHandler("boom", func(message string) {
+		panic(message)
+	})
+
+	queue.EnqueueTask("boom", "failed")
+
+	descriptor, _ := queue.Dequeue(0)
+	func() {
+		defer func() {
+			recover()
+		}()
+
+		descriptor.UserCall.(func(string))(descriptor.Args[0].(string))
+	}()
+
+	queue.Close()
+
+	queue, err = goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not reopen queue %v", err)
+		return
+	}
+	defer queue.Close()
+
+	queue.RegisterHandler("boom", func(message string) {})
+
+	replayed, err := queue.Replay()
+	if err != nil || replayed != 1 {
+		t.Errorf("expected the task that panicked to be replayed, got %d/%v", replayed, err)
+	}
+}
+
+func TestPersistentFunctionQueueSkipsCorruptRecord(t *testing.T) {
+	directory, err := ioutil.TempDir("", "goethe-persistent")
+	if err != nil {
+		t.Errorf("could not create directory %v", err)
+		return
+	}
+	defer os.RemoveAll(directory)
+
+	queue, err := goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not create queue %v", err)
+		return
+	}
+
+	queue.RegisterHandler("echo", func(message string) {})
+
+	for _, message := range []string{"first", "second", "third"} {
+		queue.EnqueueTask("echo", message)
+	}
+
+	queue.Close()
+
+	// Damage the log between the first and second records
+	logPath := filepath.Join(directory, "goethe-queue.log")
+	raw, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Errorf("could not read log %v", err)
+		return
+	}
+
+	firstLine := bytes.IndexByte(raw, '\n') + 1
+	damaged := make([]byte, 0, len(raw)+16)
+	damaged = append(damaged, raw[:firstLine]...)
+	damaged = append(damaged, `{"op":"enq`+"\n"...)
+	damaged = append(damaged, raw[firstLine:]...)
+	if err = ioutil.WriteFile(logPath, damaged, 0600); err != nil {
+		t.Errorf("could not write log %v", err)
+		return
+	}
+
+	queue, err = goethe.NewPersistentFunctionQueue(directory, 10)
+	if err != nil {
+		t.Errorf("could not reopen queue %v", err)
+		return
+	}
+	defer queue.Close()
+
+	queue.RegisterHandler("echo", func(message string) {})
+
+	replayed, err := queue.Replay()
+	if err != nil || replayed != 3 {
+		t.Errorf("expected the records after the damaged line to be replayed, got %d/%v", replayed, err)
+		return
+	}
+
+	for _, expected := range []string{"first", "second", "third"} {
+		descriptor, _ := queue.Dequeue(0)
+		if descriptor.Args[0] != expected {
+			t.Errorf("expected %s, got %v", expected, descriptor.Args[0])
+		}
+	}
+}
@@ -247,3 +247,127 @@ func getTID(ret chan int64) {
 
 	ret <- ethe.GetThreadID()
 }
+
+func TestPoolSubmitReturnsResults(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	errors := goethe.NewBoundedErrorQueue(10)
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("SubmitPool", 1, 1, 1*time.Minute, funcQueue, errors)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("error starting pool %v", err)
+		return
+	}
+
+	future, err := pool.Submit(func(a, b int) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return a * b, nil
+	}, 3, 4)
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	results, err := future.Get(5 * time.Second)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+		return
+	}
+
+	if results[0].(int) != 12 {
+		t.Errorf("expected 12, got %v", results[0])
+		return
+	}
+
+	if future.GetThreadID() < 10 {
+		t.Errorf("job should have run on a goethe thread, got %d", future.GetThreadID())
+		return
+	}
+
+	queued := future.GetQueuedTime()
+	started := future.GetStartedTime()
+	finished := future.GetFinishedTime()
+
+	if started.Before(queued) {
+		t.Errorf("started %v before it was queued %v", started, queued)
+		return
+	}
+	if finished.Sub(started) < 10*time.Millisecond {
+		t.Errorf("job should have taken at least 10ms, took %v", finished.Sub(started))
+		return
+	}
+
+	_, err = pool.Submit(func(i int) {}, "not an int")
+	if err == nil {
+		t.Error("expected error submitting mismatched arguments")
+		return
+	}
+}
+
+func TestPoolSubmitErrorGoesToErrorQueue(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	errorQueue := goethe.NewBoundedErrorQueue(10)
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("SubmitErrorPool", 1, 1, 1*time.Minute, funcQueue, errorQueue)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.Start()
+
+	expected := goethe.ErrEmptyQueue
+
+	future, err := pool.Submit(func() error {
+		return expected
+	})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	_, err = future.Get(5 * time.Second)
+	if err != expected {
+		t.Errorf("expected error from future, got %v", err)
+		return
+	}
+
+	for lcv := 0; lcv < 100 && errorQueue.IsEmpty(); lcv++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	info, found := errorQueue.Dequeue()
+	if !found {
+		t.Error("error was not placed on the error queue")
+		return
+	}
+
+	if info.GetError() != expected {
+		t.Errorf("unexpected error on error queue %v", info.GetError())
+		return
+	}
+
+	if info.GetThreadID() != future.GetThreadID() {
+		t.Errorf("error queue tid %d differs from future tid %d", info.GetThreadID(), future.GetThreadID())
+		return
+	}
+
+	pool.Close()
+
+	_, err = pool.Submit(func() {})
+	if err != goethe.ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+		return
+	}
+}
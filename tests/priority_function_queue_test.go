@@ -0,0 +1,190 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func compareInts(a interface{}, b interface{}) int {
+	aInt := a.(int)
+	bInt := b.(int)
+
+	if aInt > bInt {
+		return 1
+	}
+	if aInt < bInt {
+		return -1
+	}
+
+	return 0
+}
+
+func dequeueName(t *testing.T, queue goethe.FunctionQueue) string {
+	descriptor, err := queue.Dequeue(0)
+	if err != nil {
+		t.Errorf("unexpected dequeue error %v", err)
+		return ""
+	}
+
+	return descriptor.Args[0].(string)
+}
+
+func TestPriorityFunctionQueueOrder(t *testing.T) {
+	queue := goethe.NewPriorityFunctionQueue(10, compareInts)
+
+	f := func(string) {}
+
+	queue.Enqueue(f, "none")
+	queue.EnqueueWithPriority(1, f, "low1")
+	queue.EnqueueWithPriority(5, f, "high1")
+	queue.EnqueueWithPriority(1, f, "low2")
+	queue.EnqueueWithPriority(5, f, "high2")
+	queue.EnqueueWithPriority(3, f, "middle")
+
+	if queue.GetSize() != 6 {
+		t.Errorf("expected six items, got %d", queue.GetSize())
+	}
+
+	expected := []string{"high1", "high2", "middle", "low1", "low2", "none"}
+	for _, name := range expected {
+		found := dequeueName(t, queue)
+		if found != name {
+			t.Errorf("expected %s, got %s", name, found)
+		}
+	}
+
+	if !queue.IsEmpty() {
+		t.Error("queue should be empty")
+	}
+
+	if _, err := queue.Dequeue(10 * time.Millisecond); err != goethe.ErrEmptyQueue {
+		t.Errorf("expected ErrEmptyQueue, got %v", err)
+	}
+}
+
+func TestPriorityFunctionQueueCapacity(t *testing.T) {
+	queue := goethe.NewPriorityFunctionQueue(1, compareInts)
+
+	f := func() {}
+
+	if err := queue.EnqueueWithPriority(1, f); err != nil {
+		t.Errorf("unexpected enqueue error %v", err)
+	}
+
+	if err := queue.EnqueueWithPriority(2, f); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+}
+
+func TestPriorityFunctionQueueAging(t *testing.T) {
+	queue := goethe.NewPriorityFunctionQueue(10, compareInts)
+
+	if err := queue.SetAgingDuration(-1); err != goethe.ErrIllegalDuration {
+		t.Errorf("expected ErrIllegalDuration, got %v", err)
+	}
+
+	queue.SetAgingDuration(20 * time.Millisecond)
+
+	f := func(string) {}
+
+	queue.EnqueueWithPriority(1, f, "old")
+	time.Sleep(30 * time.Millisecond)
+	queue.EnqueueWithPriority(5, f, "new")
+
+	expected := []string{"old", "new"}
+	for _, name := range expected {
+		found := dequeueName(t, queue)
+		if found != name {
+			t.Errorf("expected %s, got %s", name, found)
+		}
+	}
+
+	if !queue.IsEmpty() {
+		t.Error("queue should be empty")
+	}
+}
+
+func TestPriorityFunctionQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	queue := goethe.NewPriorityFunctionQueue(10, compareInts)
+
+	order := make(chan string, 4)
+	release := make(chan bool)
+
+	queue.EnqueueWithPriority(10, func() {
+		<-release
+	})
+
+	record := func(name string) {
+		order <- name
+	}
+
+	queue.Enqueue(record, "background")
+	queue.EnqueueWithPriority(1, record, "normal")
+	queue.EnqueueWithPriority(5, record, "interactive")
+
+	pool, err := ethe.NewPool("PriorityFunctionQueuePool", 1, 1, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.Start()
+	close(release)
+
+	expected := []string{"interactive", "normal", "background"}
+	for _, name := range expected {
+		select {
+		case found := <-order:
+			if found != name {
+				t.Errorf("expected %s, got %s", name, found)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("pool did not run %s", name)
+			return
+		}
+	}
+}
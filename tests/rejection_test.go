@@ -0,0 +1,392 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+// newFullPool returns a started pool whose only thread is blocked until release
+// is closed and whose FunctionQueue of capacity one holds the returned future
+func newFullPool(name string, policy goethe.RejectionPolicy,
+	blockTimeout time.Duration) (goethe.Pool, goethe.Future, chan bool, *[]*goethe.FunctionDescriptor, error) {
+	ethe := goethe.GetGoethe()
+
+	pool, err := ethe.NewPool(name, 1, 1, 1*time.Minute, goethe.NewBoundedFunctionQueue(1), nil)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("could not create pool %v", err)
+	}
+
+	if err = pool.SetRejectionPolicy(policy, blockTimeout); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("could not set rejection policy %v", err)
+	}
+
+	rejected := make([]*goethe.FunctionDescriptor, 0)
+	pool.SetRejectionHandler(func(descriptor *goethe.FunctionDescriptor) {
+		rejected = append(rejected, descriptor)
+	})
+
+	pool.Start()
+
+	started := make(chan bool)
+	release := make(chan bool)
+	pool.Submit(func() {
+		started <- true
+		<-release
+	})
+
+	<-started
+
+	queued, err := pool.Submit(func() {})
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("could not fill function queue %v", err)
+	}
+
+	return pool, queued, release, &rejected, nil
+}
+
+func TestRejectAbort(t *testing.T) {
+	pool, _, release, rejected, err := newFullPool("RejectAbortPool", goethe.RejectAbort, 0)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	defer pool.Close()
+	defer close(release)
+
+	if _, err := pool.Submit(func() {}); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if len(*rejected) != 1 || pool.GetStatistics().RejectedTasks != 1 {
+		t.Errorf("expected one rejection, got %d/%d", len(*rejected), pool.GetStatistics().RejectedTasks)
+	}
+}
+
+func TestRejectCallerRuns(t *testing.T) {
+	pool, _, release, rejected, err := newFullPool("RejectCallerRunsPool", goethe.RejectCallerRuns, 0)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	defer pool.Close()
+	defer close(release)
+
+	ran := false
+	future, err := pool.Submit(func() {
+		ran = true
+	})
+	if err != nil {
+		t.Errorf("unexpected submit error %v", err)
+		return
+	}
+
+	if !ran || !future.IsDone() {
+		t.Error("function was not run by the caller")
+	}
+
+	if len(*rejected) != 1 {
+		t.Errorf("expected one rejection, got %d", len(*rejected))
+	}
+}
+
+func TestRejectCallerRunsKeepsContextAndReportsError(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	errorQueue := goethe.NewBoundedErrorQueue(10)
+
+	// Never started, so the function queue stays full
+	pool, err := ethe.NewPool("RejectCallerRunsContextPool", 1, 1, 1*time.Minute,
+		goethe.NewBoundedFunctionQueue(1), errorQueue)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.SetRejectionPolicy(goethe.RejectCallerRuns, 0)
+	pool.Submit(func() {})
+
+	outer := context.WithValue(context.Background(), contextKey("level"), "outer")
+	inner := context.WithValue(context.Background(), contextKey("level"), "inner")
+
+	levels := make(chan interface{}, 2)
+	ethe.GoContext(outer, func() {
+		pool.SubmitContext(inner, func() error {
+			ctx, _ := ethe.GetContext()
+			levels <- ctx.Value(contextKey("level"))
+
+			return errors.New("caller ran")
+		})
+
+		ctx, _ := ethe.GetContext()
+		levels <- ctx.Value(contextKey("level"))
+	})
+
+	for _, expected := range []string{"inner", "outer"} {
+		select {
+		case level := <-levels:
+			if level != expected {
+				t.Errorf("expected the %s context, got %v", expected, level)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("never got the %s context", expected)
+			return
+		}
+	}
+
+	info, found := errorQueue.Dequeue()
+	if !found {
+		t.Error("error of the function run by the caller was not on the error queue")
+		return
+	}
+
+	if info.GetError().Error() != "caller ran" {
+		t.Errorf("unexpected error %v", info.GetError())
+	}
+}
+
+func TestRejectDiscardOldest(t *testing.T) {
+	pool, queued, release, rejected, err := newFullPool("RejectDiscardOldestPool", goethe.RejectDiscardOldest, 0)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	defer pool.Close()
+
+	newest, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("unexpected submit error %v", err)
+		return
+	}
+
+	if !queued.IsCancelled() {
+		t.Error("oldest function was not cancelled")
+	}
+
+	if len(*rejected) != 1 {
+		t.Errorf("expected one rejection, got %d", len(*rejected))
+	}
+
+	close(release)
+
+	if _, err = newest.Get(5 * time.Second); err != nil {
+		t.Errorf("newest function did not run %v", err)
+	}
+}
+
+func TestRejectDiscardNewest(t *testing.T) {
+	pool, queued, release, rejected, err := newFullPool("RejectDiscardNewestPool", goethe.RejectDiscardNewest, 0)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	defer pool.Close()
+	defer close(release)
+
+	newest, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("unexpected submit error %v", err)
+		return
+	}
+
+	if !newest.IsCancelled() || queued.IsCancelled() {
+		t.Errorf("expected only the newest function to be cancelled, %v/%v", newest.IsCancelled(), queued.IsCancelled())
+	}
+
+	if len(*rejected) != 1 {
+		t.Errorf("expected one rejection, got %d", len(*rejected))
+	}
+}
+
+func TestRejectBlock(t *testing.T) {
+	pool, _, release, rejected, err := newFullPool("RejectBlockPool", goethe.RejectBlock, 20*time.Millisecond)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	defer pool.Close()
+
+	if _, err := pool.Submit(func() {}); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity after blocking, got %v", err)
+	}
+
+	if len(*rejected) != 1 {
+		t.Errorf("expected one rejection, got %d", len(*rejected))
+	}
+
+	pool.SetRejectionPolicy(goethe.RejectBlock, -1)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	future, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("unexpected submit error %v", err)
+		return
+	}
+
+	if _, err = future.Get(5 * time.Second); err != nil {
+		t.Errorf("blocked function did not run %v", err)
+	}
+
+	if err = pool.SetRejectionPolicy(goethe.RejectBlock, -2); err != goethe.ErrIllegalDuration {
+		t.Errorf("expected ErrIllegalDuration, got %v", err)
+	}
+}
+
+func TestRejectBlockOnPoolNotStarted(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(1)
+
+	pool, err := ethe.NewPool("RejectBlockNotStartedPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.SetRejectionPolicy(goethe.RejectBlock, -1)
+	pool.Submit(func() {})
+
+	submitted := make(chan error)
+	go func() {
+		_, err := pool.Submit(func() {})
+		submitted <- err
+	}()
+
+	select {
+	case err := <-submitted:
+		t.Errorf("submit should have blocked on the full queue, got %v", err)
+		return
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Room made without the pool running notifies the blocked submit
+	funcQueue.Dequeue(0)
+
+	select {
+	case err := <-submitted:
+		if err != nil {
+			t.Errorf("unexpected submit error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("blocked submit never noticed the room on the queue")
+	}
+}
+
+// syncCallbackQueue calls the state change callback on the thread that changed the queue
+type syncCallbackQueue struct {
+	goethe.FunctionQueue
+	callback func(goethe.FunctionQueue)
+}
+
+func (queue *syncCallbackQueue) Enqueue(userCall interface{}, args ...interface{}) error {
+	err := queue.FunctionQueue.Enqueue(userCall, args...)
+	if err == nil && queue.callback != nil {
+		queue.callback(queue)
+	}
+
+	return err
+}
+
+func (queue *syncCallbackQueue) Dequeue(d time.Duration) (*goethe.FunctionDescriptor, error) {
+	descriptor, err := queue.FunctionQueue.Dequeue(d)
+	if err == nil && queue.callback != nil {
+		queue.callback(queue)
+	}
+
+	return descriptor, err
+}
+
+func (queue *syncCallbackQueue) SetStateChangeCallback(callback func(goethe.FunctionQueue)) {
+	queue.callback = callback
+}
+
+func TestRejectBlockWithSynchronousCallback(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := &syncCallbackQueue{
+		FunctionQueue: goethe.NewBoundedFunctionQueue(1),
+	}
+
+	pool, err := ethe.NewPool("RejectBlockSyncCallbackPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.SetRejectionPolicy(goethe.RejectBlock, -1)
+	pool.Submit(func() {})
+
+	submitted := make(chan error)
+	go func() {
+		_, err := pool.Submit(func() {})
+		submitted <- err
+	}()
+
+	select {
+	case err := <-submitted:
+		t.Errorf("submit should have blocked on the full queue, got %v", err)
+		return
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	funcQueue.Dequeue(0)
+
+	select {
+	case err := <-submitted:
+		if err != nil {
+			t.Errorf("unexpected submit error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("blocked submit deadlocked with the callback of the queue")
+	}
+}
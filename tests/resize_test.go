@@ -0,0 +1,249 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func waitForThreadCount(pool goethe.Pool, expected int32) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pool.GetCurrentThreadCount() == expected {
+			return true
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return false
+}
+
+func TestSetMinThreadsGrowsPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("GrowMinPool", 1, 4, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	err = pool.SetMinThreads(3)
+	if err != nil {
+		t.Errorf("could not set minimum threads %v", err)
+		return
+	}
+
+	if pool.GetMinThreads() != 3 {
+		t.Errorf("expected minimum of 3, got %d", pool.GetMinThreads())
+	}
+
+	if pool.GetCurrentThreadCount() != 3 {
+		t.Errorf("expected 3 threads, got %d", pool.GetCurrentThreadCount())
+	}
+}
+
+func TestSetMaxThreadsShrinksPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("ShrinkMaxPool", 3, 3, 1*time.Hour, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	err = pool.SetMinThreads(1)
+	if err != nil {
+		t.Errorf("could not set minimum threads %v", err)
+		return
+	}
+
+	err = pool.SetMaxThreads(1)
+	if err != nil {
+		t.Errorf("could not set maximum threads %v", err)
+		return
+	}
+
+	if !waitForThreadCount(pool, 1) {
+		t.Errorf("pool did not shrink to one thread, it has %d", pool.GetCurrentThreadCount())
+	}
+}
+
+func TestSetMaxThreadsGrowsWithBacklog(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("GrowMaxPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	defer close(release)
+
+	for lcv := 0; lcv < 3; lcv++ {
+		funcQueue.Enqueue(func() {
+			started <- true
+			<-release
+		})
+	}
+
+	<-started
+
+	err = pool.SetMaxThreads(3)
+	if err != nil {
+		t.Errorf("could not set maximum threads %v", err)
+		return
+	}
+
+	for lcv := 0; lcv < 2; lcv++ {
+		select {
+		case <-started:
+			break
+		case <-time.After(5 * time.Second):
+			t.Errorf("only %d of the queued jobs started after growing the pool", lcv+1)
+			return
+		}
+	}
+}
+
+func TestSetIdleDecayDurationDecaysIdleThreads(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("IdleDecayPool", 0, 1, 1*time.Hour, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	future, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+	future.Get(5 * time.Second)
+
+	err = pool.SetIdleDecayDuration(10 * time.Millisecond)
+	if err != nil {
+		t.Errorf("could not set idle decay duration %v", err)
+		return
+	}
+
+	if pool.GetIdleDecayDuration() != 10*time.Millisecond {
+		t.Errorf("unexpected idle decay duration %v", pool.GetIdleDecayDuration())
+	}
+
+	if !waitForThreadCount(pool, 0) {
+		t.Errorf("idle thread did not decay, pool has %d threads", pool.GetCurrentThreadCount())
+	}
+}
+
+func TestResizeValidation(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("ResizeValidationPool", 2, 4, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if pool.SetMinThreads(-1) == nil {
+		t.Error("negative minimum should have failed")
+	}
+	if pool.SetMinThreads(5) == nil {
+		t.Error("minimum greater than maximum should have failed")
+	}
+	if pool.SetMaxThreads(0) == nil {
+		t.Error("maximum of zero should have failed")
+	}
+	if pool.SetMaxThreads(1) == nil {
+		t.Error("maximum less than minimum should have failed")
+	}
+	if pool.SetIdleDecayDuration(-1) != goethe.ErrIllegalDuration {
+		t.Error("negative idle decay duration should have failed")
+	}
+
+	if pool.GetMinThreads() != 2 || pool.GetMaxThreads() != 4 {
+		t.Errorf("failed changes modified the pool %d/%d", pool.GetMinThreads(), pool.GetMaxThreads())
+	}
+}
@@ -0,0 +1,274 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/jwells131313/goethe"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRingFunctionQueueOrderAndCapacity(t *testing.T) {
+	queue := goethe.NewRingFunctionQueue(3)
+
+	// Rounded up to a power of two
+	if queue.GetCapacity() != 4 {
+		t.Errorf("expected capacity of 4, got %d", queue.GetCapacity())
+	}
+
+	f := func(string) {}
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := queue.Enqueue(f, name); err != nil {
+			t.Errorf("unexpected enqueue error %v", err)
+			return
+		}
+	}
+
+	if err := queue.Enqueue(f, "e"); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if queue.GetSize() != 4 {
+		t.Errorf("expected size of 4, got %d", queue.GetSize())
+	}
+
+	expected := []string{"a", "b"}
+	found := []string{dequeueName(t, queue), dequeueName(t, queue)}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	// Wrap around the buffer
+	queue.Enqueue(f, "e")
+	queue.Enqueue(f, "f")
+
+	expected = []string{"c", "d", "e", "f"}
+	if found := dequeueAllNames(t, queue); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	if _, err := queue.Dequeue(0); err != goethe.ErrEmptyQueue {
+		t.Errorf("expected ErrEmptyQueue, got %v", err)
+	}
+}
+
+func TestRingFunctionQueueZeroCapacity(t *testing.T) {
+	queue := goethe.NewRingFunctionQueue(0)
+
+	if queue.GetCapacity() != 0 {
+		t.Errorf("expected capacity of 0, got %d", queue.GetCapacity())
+	}
+
+	if err := queue.Enqueue(func() {}); err != goethe.ErrAtCapacity {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+
+	if !queue.IsEmpty() {
+		t.Errorf("queue should be empty, size is %d", queue.GetSize())
+	}
+}
+
+func TestRingFunctionQueueDequeueWaits(t *testing.T) {
+	queue := goethe.NewRingFunctionQueue(10)
+
+	f := func(string) {}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		queue.Enqueue(f, "late")
+	}()
+
+	descriptor, err := queue.Dequeue(5 * time.Second)
+	if err != nil {
+		t.Errorf("unexpected dequeue error %v", err)
+		return
+	}
+
+	if descriptor.Args[0] != "late" {
+		t.Errorf("unexpected function %v", descriptor.Args)
+	}
+
+	start := time.Now()
+	if _, err = queue.Dequeue(50 * time.Millisecond); err != goethe.ErrEmptyQueue {
+		t.Errorf("expected ErrEmptyQueue, got %v", err)
+	}
+
+	if time.Since(start) < 50*time.Millisecond {
+		t.Errorf("dequeue returned before the duration, %v", time.Since(start))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err = queue.DequeueContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRingFunctionQueueManyProducersAndConsumers(t *testing.T) {
+	queue := goethe.NewRingFunctionQueue(64)
+
+	const producers = 4
+	const perProducer = 5000
+
+	f := func(int) {}
+
+	var wg sync.WaitGroup
+	for producer := 0; producer < producers; producer++ {
+		wg.Add(1)
+		go func(base int) {
+			defer wg.Done()
+
+			for lcv := 0; lcv < perProducer; lcv++ {
+				for queue.Enqueue(f, base+lcv) == goethe.ErrAtCapacity {
+					time.Sleep(time.Microsecond)
+				}
+			}
+		}(producer * perProducer)
+	}
+
+	var mux sync.Mutex
+	seen := make(map[int]bool)
+	var duplicates int32
+
+	var consumers sync.WaitGroup
+	for consumer := 0; consumer < 4; consumer++ {
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+
+			for {
+				descriptor, err := queue.Dequeue(200 * time.Millisecond)
+				if err != nil {
+					return
+				}
+
+				value := descriptor.Args[0].(int)
+
+				mux.Lock()
+				if seen[value] {
+					atomic.AddInt32(&duplicates, 1)
+				}
+				seen[value] = true
+				mux.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	consumers.Wait()
+
+	if duplicates != 0 {
+		t.Errorf("got %d duplicate functions", duplicates)
+	}
+
+	if len(seen) != producers*perProducer {
+		t.Errorf("expected %d functions, got %d", producers*perProducer, len(seen))
+	}
+
+	if !queue.IsEmpty() {
+		t.Errorf("queue should be empty, size is %d", queue.GetSize())
+	}
+}
+
+func TestRingFunctionQueueCoalescesNotifications(t *testing.T) {
+	queue := goethe.NewRingFunctionQueue(1024)
+
+	var calls int32
+	sizes := make(chan int, 1024)
+	queue.SetStateChangeCallback(func(q goethe.FunctionQueue) {
+		atomic.AddInt32(&calls, 1)
+		sizes <- q.GetSize()
+	})
+
+	f := func() {}
+	for lcv := 0; lcv < 1000; lcv++ {
+		queue.Enqueue(f)
+	}
+
+	// Wait for the last notification to see every enqueue
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case size := <-sizes:
+			done = size == 1000
+		case <-timeout:
+			t.Errorf("never notified of the full queue")
+			return
+		}
+	}
+
+	if atomic.LoadInt32(&calls) >= 1000 {
+		t.Errorf("expected notifications to be coalesced, got %d", calls)
+	}
+}
+
+func TestRingFunctionQueueInPool(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	queue := goethe.NewRingFunctionQueue(1024)
+
+	var ran int32
+	for lcv := 0; lcv < 100; lcv++ {
+		queue.Enqueue(func() {
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+
+	pool, err := ethe.NewPool("RingFunctionQueuePool", 2, 4, 5*time.Minute, queue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	pool.Start()
+	pool.Shutdown(true)
+	pool.AwaitTermination(5 * time.Second)
+
+	if atomic.LoadInt32(&ran) != 100 {
+		t.Errorf("expected 100 functions to run, ran %d", ran)
+	}
+}
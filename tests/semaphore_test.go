@@ -0,0 +1,191 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"errors"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestSemaphorePermits(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	sem, err := ethe.NewSemaphore(2)
+	if err != nil {
+		t.Errorf("could not create semaphore %v", err)
+		return
+	}
+
+	gotIt, err := sem.TryAcquire(2, 0)
+	if err != nil || !gotIt {
+		t.Errorf("could not take both permits %v/%v", gotIt, err)
+		return
+	}
+
+	gotIt, _ = sem.TryAcquire(1, 0)
+	if gotIt {
+		t.Error("took a permit that was not available")
+	}
+
+	gotIt, _ = sem.TryAcquire(1, 20*time.Millisecond)
+	if gotIt {
+		t.Error("took a permit that was not available after waiting")
+	}
+
+	sem.Release(1)
+	if sem.GetAvailablePermits() != 1 {
+		t.Errorf("expected one available permit, got %d", sem.GetAvailablePermits())
+	}
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- sem.Acquire(2)
+	}()
+
+	select {
+	case <-acquired:
+		t.Error("acquired two permits when only one was available")
+		return
+	case <-time.After(20 * time.Millisecond):
+		break
+	}
+
+	sem.Release(1)
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Acquire did not wake up after Release")
+	}
+
+	if sem.GetAvailablePermits() != 0 {
+		t.Errorf("expected no available permits, got %d", sem.GetAvailablePermits())
+	}
+}
+
+func TestSemaphoreIllegalArguments(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	_, err := ethe.NewSemaphore(-1)
+	if err != goethe.ErrIllegalPermits {
+		t.Errorf("expected ErrIllegalPermits, got %v", err)
+	}
+
+	sem, _ := ethe.NewSemaphore(1)
+	if _, err = sem.TryAcquire(-1, 0); err != goethe.ErrIllegalPermits {
+		t.Errorf("expected ErrIllegalPermits, got %v", err)
+	}
+	if _, err = sem.TryAcquire(1, -2); err != goethe.ErrIllegalDuration {
+		t.Errorf("expected ErrIllegalDuration, got %v", err)
+	}
+	if err = sem.Release(-1); err != goethe.ErrIllegalPermits {
+		t.Errorf("expected ErrIllegalPermits, got %v", err)
+	}
+
+	tracked, _ := ethe.NewTrackedSemaphore(1, nil)
+	if err = tracked.Acquire(1); err != goethe.ErrNotGoetheThread {
+		t.Errorf("expected ErrNotGoetheThread, got %v", err)
+	}
+}
+
+func TestTrackedSemaphoreReportsLeak(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	leaks := goethe.NewBoundedErrorQueue(10)
+	sem, err := ethe.NewTrackedSemaphore(3, leaks)
+	if err != nil {
+		t.Errorf("could not create semaphore %v", err)
+		return
+	}
+
+	held := make(chan int32)
+	leakyTid, _ := ethe.Go(func() {
+		sem.Acquire(2)
+		held <- sem.GetHeldPermits()
+	})
+
+	if count := <-held; count != 2 {
+		t.Errorf("expected thread to hold 2 permits, got %d", count)
+	}
+
+	politeDone := make(chan bool)
+	ethe.Go(func() {
+		sem.Acquire(1)
+		sem.Release(1)
+		politeDone <- true
+	})
+	<-politeDone
+
+	var info goethe.ErrorInformation
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var found bool
+		info, found = leaks.Dequeue()
+		if found {
+			break
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if info == nil {
+		t.Error("leaked permits were not reported")
+		return
+	}
+
+	if info.GetThreadID() != leakyTid || !errors.Is(info.GetError(), goethe.ErrPermitsLeaked) {
+		t.Errorf("unexpected leak report from thread %d: %v", info.GetThreadID(), info.GetError())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, found := leaks.Dequeue(); found {
+		t.Error("thread that released its permits was reported as leaking")
+	}
+
+	if sem.GetAvailablePermits() != 1 {
+		t.Errorf("leaked permits should not be given back, %d available", sem.GetAvailablePermits())
+	}
+}
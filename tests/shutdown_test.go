@@ -0,0 +1,318 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsQueue(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("DrainPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	funcQueue.Enqueue(func() {
+		started <- true
+		<-release
+	})
+
+	<-started
+
+	var ran int32
+	for lcv := 0; lcv < 3; lcv++ {
+		funcQueue.Enqueue(func() {
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+
+	pool.Shutdown(true)
+
+	if pool.IsTerminated() {
+		t.Error("pool terminated while a job was still running")
+	}
+
+	close(release)
+
+	terminated, err := pool.AwaitTermination(5 * time.Second)
+	if err != nil {
+		t.Errorf("could not await termination %v", err)
+		return
+	}
+	if !terminated {
+		t.Error("pool did not terminate")
+		return
+	}
+
+	if atomic.LoadInt32(&ran) != 3 {
+		t.Errorf("expected all three queued jobs to run, %d ran", ran)
+	}
+
+	if !funcQueue.IsEmpty() {
+		t.Errorf("function queue was not drained, it has %d jobs", funcQueue.GetSize())
+	}
+}
+
+func TestShutdownWithoutDrainLeavesQueue(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("NoDrainPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	funcQueue.Enqueue(func() {
+		started <- true
+		<-release
+	})
+
+	<-started
+
+	funcQueue.Enqueue(func() {})
+	funcQueue.Enqueue(func() {})
+
+	pool.Shutdown(false)
+	close(release)
+
+	terminated, err := pool.AwaitTermination(5 * time.Second)
+	if err != nil || !terminated {
+		t.Errorf("pool did not terminate %v", err)
+		return
+	}
+
+	if funcQueue.GetSize() != 2 {
+		t.Errorf("expected two jobs left on the queue, there are %d", funcQueue.GetSize())
+	}
+}
+
+func TestShutdownNowReturnsUnrunJobs(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("ShutdownNowPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	funcQueue.Enqueue(func() {
+		started <- true
+		<-release
+	})
+
+	<-started
+
+	funcQueue.Enqueue(func() {})
+	future, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	unrun := pool.ShutdownNow()
+	close(release)
+
+	if len(unrun) != 2 {
+		t.Errorf("expected two jobs that never ran, got %d", len(unrun))
+	}
+
+	if !future.IsCancelled() {
+		t.Error("future of a job that never ran was not cancelled")
+	}
+
+	_, err = pool.Submit(func() {})
+	if err != goethe.ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed submitting to a shutdown pool, got %v", err)
+	}
+
+	terminated, err := pool.AwaitTermination(5 * time.Second)
+	if err != nil || !terminated {
+		t.Errorf("pool did not terminate %v", err)
+	}
+}
+
+func TestShutdownNowReturnsDelayedJobs(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewDelayFunctionQueue(10)
+
+	pool, err := ethe.NewPool("ShutdownNowDelayPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	var ran int32
+	for lcv := 0; lcv < 2; lcv++ {
+		funcQueue.EnqueueAfter(time.Hour, func() {
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+
+	unrun := pool.ShutdownNow()
+	if len(unrun) != 2 {
+		t.Errorf("expected the two jobs that are not yet due, got %d", len(unrun))
+	}
+
+	if funcQueue.GetDelayedSize() != 0 {
+		t.Errorf("jobs that are not yet due were left on the queue, %d", funcQueue.GetDelayedSize())
+	}
+
+	terminated, err := pool.AwaitTermination(5 * time.Second)
+	if err != nil || !terminated {
+		t.Errorf("pool did not terminate %v", err)
+	}
+
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Errorf("jobs that were not yet due should not have run, %d ran", ran)
+	}
+}
+
+func TestAwaitTerminationWakesIdleThreads(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("IdleShutdownPool", 2, 2, 1*time.Hour, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	terminated, err := pool.AwaitTermination(0)
+	if err != nil || terminated {
+		t.Errorf("running pool should not be terminated %v/%v", terminated, err)
+		return
+	}
+
+	_, err = pool.AwaitTermination(-2)
+	if err != goethe.ErrIllegalDuration {
+		t.Errorf("expected ErrIllegalDuration, got %v", err)
+	}
+
+	pool.Close()
+
+	terminated, err = pool.AwaitTermination(5 * time.Second)
+	if err != nil || !terminated {
+		t.Errorf("idle pool did not terminate %v", err)
+	}
+}
+
+func TestAwaitTerminationFromPoolThread(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("AwaitSelfPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	future, err := pool.Submit(func() error {
+		_, err := pool.AwaitTermination(-1)
+		return err
+	})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	_, err = future.Get(5 * time.Second)
+	if err != goethe.ErrNotCalledOnCorrectThread {
+		t.Errorf("expected ErrNotCalledOnCorrectThread, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/jwells131313/goethe"
+	"testing"
+	"time"
+)
+
+func TestPoolStatistics(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+	errorQueue := goethe.NewBoundedErrorQueue(10)
+
+	pool, err := ethe.NewPool("StatisticsPool", 1, 1, 1*time.Minute, funcQueue, errorQueue)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+
+	pool.SetPanicPolicy(goethe.PanicRecover)
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	futures := make([]goethe.Future, 0)
+	for lcv := 0; lcv < 3; lcv++ {
+		future, err := pool.Submit(func() {})
+		if err != nil {
+			t.Errorf("could not submit job %v", err)
+			return
+		}
+		futures = append(futures, future)
+	}
+
+	future, _ := pool.Submit(func() error {
+		return errors.New("failed job")
+	})
+	futures = append(futures, future)
+
+	future, _ = pool.Submit(func() {
+		panic("panicked job")
+	})
+	futures = append(futures, future)
+
+	for _, future := range futures {
+		future.Get(5 * time.Second)
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	future, _ = pool.Submit(func() {
+		started <- true
+		<-release
+	})
+
+	<-started
+
+	stats := pool.GetStatistics()
+	if stats.ActiveThreads != 1 || stats.IdleThreads != 0 {
+		t.Errorf("expected one active and no idle threads, got %d/%d", stats.ActiveThreads, stats.IdleThreads)
+	}
+
+	close(release)
+	future.Get(5 * time.Second)
+
+	pool.Close()
+
+	_, err = pool.Submit(func() {})
+	if err != goethe.ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+
+	pool.AwaitTermination(5 * time.Second)
+
+	stats = pool.GetStatistics()
+	if stats.CompletedTasks != 4 {
+		t.Errorf("expected 4 completed tasks, got %d", stats.CompletedTasks)
+	}
+	if stats.FailedTasks != 2 {
+		t.Errorf("expected 2 failed tasks, got %d", stats.FailedTasks)
+	}
+	if stats.RejectedTasks != 1 {
+		t.Errorf("expected 1 rejected task, got %d", stats.RejectedTasks)
+	}
+	if stats.ThreadsCreated != 1 || stats.PeakThreads != 1 {
+		t.Errorf("expected one thread created, got %d with peak %d", stats.ThreadsCreated, stats.PeakThreads)
+	}
+	if stats.ExecutionTime.Count != 6 || stats.QueueWaitTime.Count != 6 {
+		t.Errorf("expected 6 execution and queue wait times, got %d/%d",
+			stats.ExecutionTime.Count, stats.QueueWaitTime.Count)
+	}
+
+	var total uint64
+	for _, count := range stats.ExecutionTime.Counts {
+		total += count
+	}
+	if total != stats.ExecutionTime.Count {
+		t.Errorf("histogram buckets add up to %d, expected %d", total, stats.ExecutionTime.Count)
+	}
+
+	if stats.ExecutionTime.Min > stats.ExecutionTime.Max {
+		t.Errorf("histogram min %v is greater than max %v", stats.ExecutionTime.Min, stats.ExecutionTime.Max)
+	}
+}
+
+func TestPoolStatisticsThreadsDecayed(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("DecayStatisticsPool", 0, 1, 10*time.Millisecond, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	future, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+	future.Get(5 * time.Second)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pool.GetStatistics().ThreadsDecayed == 1 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := pool.GetStatistics()
+	if stats.ThreadsCreated != 1 || stats.ThreadsDecayed != 1 {
+		t.Errorf("expected one thread created and decayed, got %d/%d", stats.ThreadsCreated, stats.ThreadsDecayed)
+	}
+}
+
+func TestPoolStatisticsCancelledTasks(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	funcQueue := goethe.NewBoundedFunctionQueue(10)
+
+	pool, err := ethe.NewPool("CancelledStatisticsPool", 1, 1, 1*time.Minute, funcQueue, nil)
+	if err != nil {
+		t.Errorf("could not create pool %v", err)
+		return
+	}
+	defer pool.Close()
+
+	err = pool.Start()
+	if err != nil {
+		t.Errorf("could not start pool %v", err)
+		return
+	}
+
+	started := make(chan bool)
+	release := make(chan bool)
+	blocker, err := pool.Submit(func() {
+		started <- true
+		<-release
+	})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	<-started
+
+	cancelled, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+	cancelled.Cancel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = pool.SubmitContext(ctx, func() {})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	last, err := pool.Submit(func() {})
+	if err != nil {
+		t.Errorf("could not submit job %v", err)
+		return
+	}
+
+	close(release)
+	blocker.Get(5 * time.Second)
+	last.Get(5 * time.Second)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pool.GetStatistics().CompletedTasks == 2 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := pool.GetStatistics()
+	if stats.CompletedTasks != 2 {
+		t.Errorf("expected 2 completed tasks, got %d", stats.CompletedTasks)
+	}
+	if stats.CancelledTasks != 2 {
+		t.Errorf("expected 2 cancelled tasks, got %d", stats.CancelledTasks)
+	}
+	if stats.FailedTasks != 0 {
+		t.Errorf("expected no failed tasks, got %d", stats.FailedTasks)
+	}
+	if stats.ExecutionTime.Count != 2 {
+		t.Errorf("expected 2 execution times, got %d", stats.ExecutionTime.Count)
+	}
+}
@@ -0,0 +1,413 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package tests
+
+import (
+	"github.com/jwells131313/goethe"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCountDownLatch(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	latch, err := ethe.NewCountDownLatch(3)
+	if err != nil {
+		t.Errorf("could not create latch %v", err)
+		return
+	}
+
+	opened, err := latch.Await(20 * time.Millisecond)
+	if err != nil || opened {
+		t.Errorf("latch should not have opened %v/%v", opened, err)
+		return
+	}
+
+	tids := make(chan int64, 3)
+	for lcv := 0; lcv < 3; lcv++ {
+		tid, _ := ethe.Go(func() {
+			latch.CountDown()
+		})
+		tids <- tid
+	}
+
+	opened, err = latch.Await(5 * time.Second)
+	if err != nil || !opened {
+		t.Errorf("latch did not open %v/%v", opened, err)
+		return
+	}
+
+	if latch.GetCount() != 0 {
+		t.Errorf("expected count of zero, got %d", latch.GetCount())
+	}
+
+	// Extra calls have no effect
+	latch.CountDown()
+
+	arrivals := latch.GetArrivals()
+	if len(arrivals) != 3 {
+		t.Errorf("expected three arrivals, got %v", arrivals)
+		return
+	}
+
+	expected := map[int64]bool{<-tids: true, <-tids: true, <-tids: true}
+	for _, arrival := range arrivals {
+		if !expected[arrival] {
+			t.Errorf("unexpected arrival %d, expected %v", arrival, expected)
+		}
+	}
+}
+
+func TestCountDownLatchLargeCount(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	latch, err := ethe.NewCountDownLatch(math.MaxInt32)
+	if err != nil {
+		t.Errorf("could not create latch %v", err)
+		return
+	}
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	// Nothing is reserved for arrivals that have not happened
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1024*1024 {
+		t.Errorf("creating the latch allocated %d bytes", allocated)
+	}
+
+	counted := make(chan bool)
+	ethe.Go(func() {
+		latch.CountDown()
+		counted <- true
+	})
+
+	<-counted
+
+	opened, err := latch.Await(20 * time.Millisecond)
+	if err != nil || opened {
+		t.Errorf("latch should not have opened %v/%v", opened, err)
+	}
+
+	if latch.GetCount() != math.MaxInt32-1 {
+		t.Errorf("expected count of %d, got %d", math.MaxInt32-1, latch.GetCount())
+	}
+}
+
+func TestCyclicBarrier(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	var actionArrivals []int64
+	barrier, err := ethe.NewCyclicBarrier(3, func(arrivals []int64) {
+		actionArrivals = arrivals
+	})
+	if err != nil {
+		t.Errorf("could not create barrier %v", err)
+		return
+	}
+
+	for generation := 0; generation < 2; generation++ {
+		results := make(chan []int64, 3)
+		tids := make(map[int64]bool)
+
+		for lcv := 0; lcv < 3; lcv++ {
+			tid, _ := ethe.Go(func() {
+				arrivals, err := barrier.Await(5 * time.Second)
+				if err != nil {
+					t.Errorf("unexpected barrier error %v", err)
+				}
+
+				results <- arrivals
+			})
+
+			tids[tid] = true
+		}
+
+		for lcv := 0; lcv < 3; lcv++ {
+			arrivals := <-results
+			if len(arrivals) != 3 {
+				t.Errorf("expected three arrivals, got %v", arrivals)
+				return
+			}
+
+			for _, arrival := range arrivals {
+				if !tids[arrival] {
+					t.Errorf("unexpected arrival %d in generation %d", arrival, generation)
+				}
+			}
+		}
+
+		if len(actionArrivals) != 3 {
+			t.Errorf("action did not see three arrivals, got %v", actionArrivals)
+		}
+	}
+
+	if barrier.IsBroken() {
+		t.Error("barrier should not be broken")
+	}
+}
+
+func TestCyclicBarrierTimeoutBreaks(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	barrier, _ := ethe.NewCyclicBarrier(3, nil)
+
+	brokenErr := make(chan error)
+	ethe.Go(func() {
+		_, err := barrier.Await(-1)
+		brokenErr <- err
+	})
+
+	for barrier.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := barrier.Await(20 * time.Millisecond)
+	if err != goethe.ErrTimeout {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+
+	select {
+	case err = <-brokenErr:
+		if err != goethe.ErrBarrierBroken {
+			t.Errorf("expected ErrBarrierBroken, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("waiting thread was not released when the barrier broke")
+		return
+	}
+
+	if !barrier.IsBroken() {
+		t.Error("barrier should be broken")
+	}
+
+	if _, err = barrier.Await(0); err != goethe.ErrBarrierBroken {
+		t.Errorf("expected ErrBarrierBroken on broken barrier, got %v", err)
+	}
+
+	barrier.Reset()
+	if barrier.IsBroken() {
+		t.Error("barrier should not be broken after Reset")
+	}
+}
+
+func TestCyclicBarrierActionPanicBreaks(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	oldPolicy := ethe.GetPanicPolicy()
+	ethe.SetPanicPolicy(goethe.PanicRecover)
+	defer ethe.SetPanicPolicy(oldPolicy)
+
+	barrier, _ := ethe.NewCyclicBarrier(2, func(arrivals []int64) {
+		panic("barrier action")
+	})
+
+	brokenErr := make(chan error)
+	ethe.Go(func() {
+		_, err := barrier.Await(-1)
+		brokenErr <- err
+	})
+
+	for barrier.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := barrier.Await(5 * time.Second)
+	panicErr, ok := err.(goethe.PanicError)
+	if !ok || panicErr.GetPanicValue() != "barrier action" {
+		t.Errorf("expected PanicError from the action, got %v", err)
+	}
+
+	select {
+	case err = <-brokenErr:
+		if err != goethe.ErrBarrierBroken {
+			t.Errorf("expected ErrBarrierBroken, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("waiting thread was not released when the action panicked")
+		return
+	}
+
+	if !barrier.IsBroken() {
+		t.Error("barrier should be broken")
+	}
+
+	// The barrier lock was released
+	barrier.Reset()
+	if barrier.IsBroken() {
+		t.Error("barrier should not be broken after Reset")
+	}
+}
+
+func TestPhaser(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	var mux sync.Mutex
+	completed := make(map[int32][]int64)
+
+	phaser, err := ethe.NewPhaser(1, func(phase int32, arrivals []int64) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		completed[phase] = arrivals
+	})
+	if err != nil {
+		t.Errorf("could not create phaser %v", err)
+		return
+	}
+
+	workerTids := make(chan int64, 2)
+	done := make(chan error, 2)
+	for lcv := 0; lcv < 2; lcv++ {
+		phaser.Register()
+
+		ethe.Go(func() {
+			workerTids <- ethe.GetThreadID()
+
+			phase, err := phaser.ArriveAndAwaitAdvance(5 * time.Second)
+			if err != nil || phase != 1 {
+				done <- err
+				return
+			}
+
+			_, err = phaser.ArriveAndDeregister()
+			done <- err
+		})
+	}
+
+	if phaser.GetRegisteredParties() != 3 {
+		t.Errorf("expected three parties, got %d", phaser.GetRegisteredParties())
+	}
+
+	phase, err := phaser.ArriveAndAwaitAdvance(5 * time.Second)
+	if err != nil || phase != 1 {
+		t.Errorf("unexpected advance %d/%v", phase, err)
+		return
+	}
+
+	for lcv := 0; lcv < 2; lcv++ {
+		if err = <-done; err != nil {
+			t.Errorf("worker failed %v", err)
+		}
+	}
+
+	// Both workers left, phase 1 still needs the main thread
+	if phaser.GetRegisteredParties() != 1 || phaser.GetPhase() != 1 || phaser.GetArrivedParties() != 0 {
+		t.Errorf("expected one party and no arrivals in phase 1, got %d/%d in %d",
+			phaser.GetRegisteredParties(), phaser.GetArrivedParties(), phaser.GetPhase())
+	}
+
+	phase, err = phaser.Arrive()
+	if err != nil || phase != 1 || phaser.GetPhase() != 2 {
+		t.Errorf("unexpected arrival %d/%v in %d", phase, err, phaser.GetPhase())
+	}
+
+	phase, err = phaser.ArriveAndDeregister()
+	if err != nil || phase != 2 {
+		t.Errorf("unexpected arrival %d/%v", phase, err)
+	}
+
+	if !phaser.IsTerminated() {
+		t.Error("phaser should be terminated with no parties")
+	}
+
+	if _, err = phaser.Register(); err != goethe.ErrPhaserTerminated {
+		t.Errorf("expected ErrPhaserTerminated, got %v", err)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	first := completed[0]
+	if len(first) != 3 {
+		t.Errorf("expected three arrivals in phase 0, got %v", first)
+		return
+	}
+
+	expected := map[int64]bool{-1: true, <-workerTids: true, <-workerTids: true}
+	for _, arrival := range first {
+		if !expected[arrival] {
+			t.Errorf("unexpected arrival %d in phase 0", arrival)
+		}
+	}
+
+	if len(completed[1]) != 1 || completed[1][0] != -1 {
+		t.Errorf("expected only the main thread in phase 1, got %v", completed[1])
+	}
+
+	arrivals, found := completed[2]
+	if !found || len(arrivals) != 0 {
+		t.Errorf("expected phase 2 to complete with no arrivals, got %v/%v", arrivals, found)
+	}
+}
+
+func TestPhaserTimedWait(t *testing.T) {
+	ethe := goethe.GetGoethe()
+
+	phaser, _ := ethe.NewPhaser(2, nil)
+
+	phase, err := phaser.ArriveAndAwaitAdvance(20 * time.Millisecond)
+	if err != goethe.ErrTimeout || phase != 0 {
+		t.Errorf("expected timeout in phase 0, got %d/%v", phase, err)
+	}
+
+	if phaser.GetArrivedParties() != 1 {
+		t.Errorf("expected one arrived party, got %d", phaser.GetArrivedParties())
+	}
+
+	if _, err = phaser.Arrive(); err != nil {
+		t.Errorf("unexpected arrive error %v", err)
+	}
+
+	phase, err = phaser.AwaitAdvance(0, 0)
+	if err != nil || phase != 1 {
+		t.Errorf("expected phase 1, got %d/%v", phase, err)
+	}
+
+	if _, err = ethe.NewPhaser(-1, nil); err != goethe.ErrIllegalCount {
+		t.Errorf("expected ErrIllegalCount, got %v", err)
+	}
+}
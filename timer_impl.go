@@ -221,7 +221,7 @@ func (timer *timerData) invoke(ethe *StandardThreadUtilities, job *timerJob) {
 
 	tl.Set(job)
 
-	invoke(job.method, job.args, job.errors)
+	invoke(job.method, job.args, job.errors, ethe.GetPanicPolicy())
 
 	if job.fixed {
 		// parent put new job on
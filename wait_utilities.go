@@ -0,0 +1,81 @@
+/*
+ * DO NOT ALTER OR REMOVE COPYRIGHT NOTICES OR THIS HEADER.
+ *
+ * Copyright (c) 2018 Oracle and/or its affiliates. All rights reserved.
+ *
+ * The contents of this file are subject to the terms of either the GNU
+ * General Public License Version 2 only ("GPL") or the Common Development
+ * and Distribution License("CDDL") (collectively, the "License").  You
+ * may not use this file except in compliance with the License.  You can
+ * obtain a copy of the License at
+ * https://glassfish.dev.java.net/public/CDDL+GPL_1_1.html
+ * or packager/legal/LICENSE.txt.  See the License for the specific
+ * language governing permissions and limitations under the License.
+ *
+ * When distributing the software, include this License Header Notice in each
+ * file and include the License file at packager/legal/LICENSE.txt.
+ *
+ * GPL Classpath Exception:
+ * Oracle designates this particular file as subject to the "Classpath"
+ * exception as provided by Oracle in the GPL Version 2 section of the License
+ * file that accompanied this code.
+ *
+ * Modifications:
+ * If applicable, add the following below the License Header, with the fields
+ * enclosed by brackets [] replaced by your own identifying information:
+ * "Portions Copyright [year] [name of copyright owner]"
+ *
+ * Contributor(s):
+ * If you wish your version of this file to be governed by only the CDDL or
+ * only the GPL Version 2, indicate your decision by adding "[Contributor]
+ * elects to include this software in this distribution under the [CDDL or GPL
+ * Version 2] license."  If you don't indicate a single choice of license, a
+ * recipient has the option to distribute your version of this file under
+ * either the CDDL, the GPL Version 2 or to extend the choice of license to
+ * its licensees as provided above.  However, if you add GPL Version 2 code
+ * and therefore, elected the GPL Version 2 license, then the option applies
+ * only if the new code is made subject to such option by the copyright
+ * holder.
+ */
+
+package goethe
+
+import (
+	"sync"
+	"time"
+)
+
+// waitFor waits on the condition until the done function returns true or
+// the given duration has passed.  The lock of the condition must be held
+// when this is called and must be usable from any go routine.  A duration
+// of -1 waits forever.  Returns the last value returned by done
+func waitFor(cond *sync.Cond, d time.Duration, done func() bool) bool {
+	if d < 0 {
+		for !done() {
+			cond.Wait()
+		}
+
+		return true
+	}
+
+	endTime := time.Now().Add(d)
+	for !done() {
+		remaining := time.Until(endTime)
+		if remaining <= 0 {
+			return false
+		}
+
+		timer := time.AfterFunc(remaining, func() {
+			cond.L.Lock()
+			defer cond.L.Unlock()
+
+			cond.Broadcast()
+		})
+
+		cond.Wait()
+
+		timer.Stop()
+	}
+
+	return true
+}